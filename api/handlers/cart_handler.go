@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go-ecommerce/api/middleware"
 	"go-ecommerce/internal/models"
 	"go-ecommerce/internal/services"
 )
@@ -22,13 +25,25 @@ func NewCartHandler(cartService *services.CartService, productService *services.
 }
 
 // POST /api/cart
-// Create new cart for user
+// Get the current user's active cart, or create one
 func (h *CartHandler) CreateCart(c *gin.Context) {
-	userID := h.getCurrentUserID(c) // Pakai method receiver
+	userID := middleware.CurrentUserID(c)
 	
-	cart := h.cartService.CreateCart(userID)
+	cart, created, err := h.cartService.CreateCart(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	
-	c.JSON(http.StatusCreated, gin.H{
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
 		"cart_id": cart.ID,
 		"user_id": cart.UserID,
 	})
@@ -37,7 +52,7 @@ func (h *CartHandler) CreateCart(c *gin.Context) {
 // GET /api/cart
 // Get current user's cart
 func (h *CartHandler) GetCart(c *gin.Context) {
-	userID := h.getCurrentUserID(c) // Pakai method receiver
+	userID := middleware.CurrentUserID(c)
 	
 	cart, exists := h.cartService.GetCartByUserID(userID)
 	if !exists {
@@ -53,7 +68,7 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 // POST /api/cart/items
 // Add item to cart (RACE CONDITION TEST)
 func (h *CartHandler) AddToCart(c *gin.Context) {
-	userID := h.getCurrentUserID(c) // Pakai method receiver
+	userID := middleware.CurrentUserID(c)
 	
 	var req models.AddToCartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -70,14 +85,29 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 	
 	// Get product info
 	product, exists := h.productService.GetProductByID(req.ProductID)
-	if !exists {
+	if !exists || product.IsDeleted() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 	
-	// Check stock
-	if product.Stock < req.Quantity {
-		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock"})
+	// Products with variants are sold per SKU
+	name, price, _, ok := product.Unit(req.SKU)
+	if !ok {
+		if req.SKU == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrSKURequired.Error()})
+		} else {
+			c.JSON(http.StatusNotFound, gin.H{"error": services.ErrSKUNotFound.Error()})
+		}
+		return
+	}
+	item := models.SKURef{ProductID: req.ProductID, SKU: req.SKU}
+	
+	// Hold stock for the cart's new total; units other carts hold are not
+	// available
+	held := cartQuantity(cart, item)
+	heldUntil, err := h.productService.HoldStock(cart.ID, item, held+req.Quantity)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	
@@ -85,59 +115,61 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 	mode := c.DefaultQuery("mode", "safe") // unsafe, safe, optimistic
 	
 	var updatedCart *models.Cart
-	var err error
 	
 	switch mode {
 	case "unsafe":
 		// RACE CONDITION VERSION
 		updatedCart, err = h.cartService.AddToCartNoLock(
 			cart.ID, 
-			req.ProductID, 
+			item, 
 			req.Quantity, 
-			product.Price, 
-			product.Name,
+			price, 
+			name,
 		)
 	case "optimistic":
 		version, _ := strconv.Atoi(c.DefaultQuery("version", "1"))
 		updatedCart, err = h.cartService.AddToCartOptimistic(
 			cart.ID,
-			req.ProductID,
+			item,
 			req.Quantity,
-			product.Price,
-			product.Name,
+			price,
+			name,
 			version,
 		)
 	default: // "safe"
 		updatedCart, err = h.cartService.AddToCartWithLock(
 			cart.ID,
-			req.ProductID,
+			item,
 			req.Quantity,
-			product.Price,
-			product.Name,
+			price,
+			name,
 		)
 	}
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.productService.HoldStock(cart.ID, item, held)
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Item added to cart",
 		"cart": updatedCart,
+		"held_until": heldUntil,
 		"mode": mode,
 	})
 }
 
-// PUT /api/cart/items/:product_id
+// PUT /api/cart/items/:product_id?sku=
 // Update cart item quantity
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
-	userID := h.getCurrentUserID(c) // Pakai method receiver
+	userID := middleware.CurrentUserID(c)
 	productID, err := strconv.Atoi(c.Param("product_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
+	item := models.SKURef{ProductID: productID, SKU: c.Query("sku")}
 	
 	var req models.UpdateCartItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -151,17 +183,34 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 	
+	// Move the hold to the new quantity before changing the cart; 0 drops it
+	quantity := *req.Quantity
+	held := cartQuantity(cart, item)
+	var heldUntil time.Time
+	if held > 0 && quantity == 0 {
+		h.productService.ReleaseHold(cart.ID, item)
+	} else if held > 0 {
+		heldUntil, err = h.productService.HoldStock(cart.ID, item, quantity)
+		if err != nil {
+			c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	
 	// Choose version based on query param
 	mode := c.DefaultQuery("mode", "safe")
 	
 	var updatedCart *models.Cart
 	if mode == "unsafe" {
-		updatedCart, err = h.cartService.UpdateCartItemQuantityRace(cart.ID, productID, req.Quantity)
+		updatedCart, err = h.cartService.UpdateCartItemQuantityRace(cart.ID, item, quantity)
 	} else {
-		updatedCart, err = h.cartService.UpdateCartItemQuantitySafe(cart.ID, productID, req.Quantity)
+		updatedCart, err = h.cartService.UpdateCartItemQuantitySafe(cart.ID, item, quantity)
 	}
 	
 	if err != nil {
+		if held > 0 {
+			h.productService.HoldStock(cart.ID, item, held)
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -169,30 +218,119 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cart updated",
 		"cart": updatedCart,
+		"held_until": heldUntil,
 		"mode": mode,
 	})
 }
 
-// Helper function (jadikan method private dengan huruf kecil)
-func (h *CartHandler) getCurrentUserID(c *gin.Context) int {
-	// Method 1: Dari query parameter (untuk testing)
-	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		userID, _ := strconv.Atoi(userIDStr)
-		return userID
+// DELETE /api/cart/items/:product_id?sku=
+// Remove an item from the cart
+func (h *CartHandler) RemoveCartItem(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
 	}
-	
-	// Method 2: Dari header (lebih realistic untuk production)
-	if userIDStr := c.GetHeader("X-User-ID"); userIDStr != "" {
-		userID, _ := strconv.Atoi(userIDStr)
-		return userID
+	item := models.SKURef{ProductID: productID, SKU: c.Query("sku")}
+
+	cart, exists := h.cartService.GetCartByUserID(userID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
 	}
-	
-	// Method 3: Dari JWT token (di real app)
-	// claims, exists := c.Get("user_claims")
-	// if exists {
-	//     return claims.(*jwt.MapClaims)["user_id"].(int)
-	// }
-	
-	// Default untuk testing
-	return 1
-}
\ No newline at end of file
+
+	updatedCart, err := h.cartService.RemoveItem(cart.ID, item)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.productService.ReleaseHold(cart.ID, item)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item removed from cart",
+		"cart":    updatedCart,
+	})
+}
+
+// DELETE /api/cart
+// Remove every item from the current user's cart
+func (h *CartHandler) ClearCart(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	cart, exists := h.cartService.GetCartByUserID(userID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	updatedCart, err := h.cartService.ClearCart(cart.ID)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	h.productService.ReleaseHolds(cart.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cart cleared",
+		"cart":    updatedCart,
+	})
+}
+
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCartNotFound), errors.Is(err, services.ErrCartItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCartClosed):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /api/admin/carts/abandoned?from=&to=&page=&limit=
+// Carts left idle with items in them, most recently abandoned first
+func (h *CartHandler) AbandonedCarts(c *gin.Context) {
+	var from, to time.Time
+	if value := c.Query("from"); value != "" {
+		t, _, err := parseDateParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date " + strconv.Quote(value)})
+			return
+		}
+		from = t
+	}
+	if value := c.Query("to"); value != "" {
+		t, dateOnly, err := parseDateParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date " + strconv.Quote(value)})
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+	page, limit := parsePagination(c)
+
+	carts, total, value := h.cartService.AbandonedCarts(from, to, page, limit)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": carts,
+		"summary": gin.H{
+			"carts": total,
+			"value": value,
+		},
+		"meta": pageMeta(models.PageRequest{Page: page, Limit: limit}, total, nil),
+	})
+}
+
+// cartQuantity is how many units of item the cart has.
+func cartQuantity(cart *models.Cart, item models.SKURef) int {
+	for _, existing := range cart.Items {
+		if existing.Ref() == item {
+			return existing.Quantity
+		}
+	}
+	return 0
+}
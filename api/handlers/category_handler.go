@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/services"
+)
+
+type CategoryHandler struct {
+	categoryService *services.CategoryService
+	productService  *services.ProductService
+}
+
+func NewCategoryHandler(categoryService *services.CategoryService, productService *services.ProductService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+		productService:  productService,
+	}
+}
+
+// GET /api/categories
+// The whole tree, children ordered by name
+func (h *CategoryHandler) GetTree(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": h.categoryService.Tree(),
+	})
+}
+
+// GET /api/categories/:slug
+// One category with its subtree and breadcrumbs from the root. Also
+// accepts an ID.
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	category, exists := h.categoryService.Lookup(c.Param("slug"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	node, exists := h.categoryService.Subtree(category.ID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        node,
+		"breadcrumbs": h.categoryService.Ancestors(category.ID),
+	})
+}
+
+// POST /api/admin/categories
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.categoryService.Create(req)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": category,
+	})
+}
+
+// PATCH /api/admin/categories/:id
+// Rename, change slug or move under another parent (parent_id 0 = top
+// level). Products below it get the new category path.
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.productService.UpdateCategory(id, req)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category updated",
+		"data":    category,
+	})
+}
+
+// DELETE /api/admin/categories/:id
+// Only empty categories: no subcategories and no products
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := h.productService.DeleteCategory(id); err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Category deleted",
+		"category_id": id,
+	})
+}
+
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCategoryCycle):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCategoryExists), errors.Is(err, services.ErrSlugTaken),
+		errors.Is(err, services.ErrCategoryInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
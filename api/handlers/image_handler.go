@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-ecommerce/internal/media"
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/services"
+)
+
+type ImageHandler struct {
+	imageService   *services.ImageService
+	productService *services.ProductService
+}
+
+func NewImageHandler(imageService *services.ImageService, productService *services.ProductService) *ImageHandler {
+	return &ImageHandler{
+		imageService:   imageService,
+		productService: productService,
+	}
+}
+
+// POST /api/admin/products/:id/images
+// Multipart form with "file" (JPEG, PNG or GIF) and optional "alt", or the
+// raw image as the body with ?alt=. The image is appended to the end.
+func (h *ImageHandler) UploadImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxImageBytes+64<<10)
+
+	var body io.Reader = c.Request.Body
+	alt := c.Query("alt")
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("image is larger than %d MB", services.MaxImageBytes>>20)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart upload needs a \"file\" field"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		body = file
+		alt = c.PostForm("alt")
+	}
+	if len(alt) > 250 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "alt text is longer than 250 characters"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, services.MaxImageBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) > services.MaxImageBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("image is larger than %d MB", services.MaxImageBytes>>20)})
+		return
+	}
+
+	image, err := h.imageService.Upload(id, data, alt)
+	if err != nil {
+		c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": image,
+	})
+}
+
+// PATCH /api/admin/products/:id/images/:image_id
+// Change alt text and/or move the image to a position (0 = main image)
+func (h *ImageHandler) UpdateImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.UpdateImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.productService.UpdateImage(id, c.Param("image_id"), req)
+	if err != nil {
+		c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": image,
+	})
+}
+
+// PUT /api/admin/products/:id/images/order
+// Body {"image_ids": [...]} with every image of the product in the new order
+func (h *ImageHandler) ReorderImages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.ReorderImages(id, req.ImageIDs)
+	if err != nil {
+		c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": product.Images,
+	})
+}
+
+// DELETE /api/admin/products/:id/images/:image_id
+// Removes the image and its files
+func (h *ImageHandler) DeleteImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.imageService.Delete(id, c.Param("image_id")); err != nil {
+		c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Image deleted",
+		"image_id": c.Param("image_id"),
+	})
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, media.ErrUnsupportedImage), errors.Is(err, services.ErrImageOrder):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTooManyImages):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/services"
+)
+
+type InventoryHandler struct {
+	productService *services.ProductService
+	orderService   *services.OrderService
+}
+
+func NewInventoryHandler(productService *services.ProductService, orderService *services.OrderService) *InventoryHandler {
+	return &InventoryHandler{
+		productService: productService,
+		orderService:   orderService,
+	}
+}
+
+// adjustmentChange turns a reason code and an optional note into the
+// ledger change for an adjustment.
+func adjustmentChange(c *gin.Context, reason models.AdjustmentReason, note string) (models.StockChange, error) {
+	if !reason.Valid() {
+		return models.StockChange{}, fmt.Errorf("unknown reason %q", reason)
+	}
+	text := string(reason)
+	if note != "" {
+		text += ": " + note
+	}
+	return models.StockChange{
+		Type:   reason.MovementType(),
+		Actor:  actorOf(c),
+		Reason: text,
+	}, nil
+}
+
+// POST /api/admin/products/:id/stock/adjust
+// Body: {"delta": 10, "reason": "received", "sku": "...", "location": "JKT", "expected_stock": 5, "note": "..."}
+// Adds a signed delta to the current stock
+func (h *InventoryHandler) AdjustStock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req struct {
+		SKU           string                  `json:"sku"`
+		Location      string                  `json:"location"`
+		Delta         int                     `json:"delta" binding:"required"`
+		ExpectedStock *int                    `json:"expected_stock" binding:"omitempty,gte=0"`
+		Reason        models.AdjustmentReason `json:"reason" binding:"required"`
+		Note          string                  `json:"note" binding:"max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	change, err := adjustmentChange(c, req.Reason, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.productService.AdjustStock([]models.StockAdjustment{{
+		ProductID:     id,
+		SKU:           req.SKU,
+		Location:      req.Location,
+		Delta:         req.Delta,
+		ExpectedStock: req.ExpectedStock,
+	}}, change)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results[0]})
+}
+
+// POST /api/admin/inventory/adjust
+// Body: {"reason": "received", "note": "...", "adjustments": [{"product_id": 1, "sku": "...", "location": "JKT", "delta": 10, "expected_stock": 5}]}
+// All lines apply or none do; -n at one location and +n at another moves stock
+func (h *InventoryHandler) BulkAdjustStock(c *gin.Context) {
+	var req struct {
+		Adjustments []models.StockAdjustment `json:"adjustments" binding:"required,min=1"`
+		Reason      models.AdjustmentReason  `json:"reason" binding:"required"`
+		Note        string                   `json:"note" binding:"max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Adjustments) > services.MaxAdjustments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d adjustments per request", services.MaxAdjustments)})
+		return
+	}
+	change, err := adjustmentChange(c, req.Reason, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.productService.AdjustStock(req.Adjustments, change)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+// GET /api/admin/products/:id/stock-history?sku=&page=&limit=
+// Ledger entries newest first, with the product's reconciliation
+func (h *InventoryHandler) StockHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	page, limit := parsePagination(c)
+
+	history, total, err := h.productService.StockHistory(id, c.Query("sku"), page, limit)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           history,
+		"reconciliation": h.productService.Reconcile(id),
+		"meta":           pageMeta(models.PageRequest{Page: page, Limit: limit}, total, nil),
+	})
+}
+
+// GET /api/admin/inventory/reconcile?product_id=&all=true
+// Replays the ledger against current stock. Only SKUs that disagree are
+// listed unless all=true.
+func (h *InventoryHandler) Reconcile(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Query("product_id"))
+	all, _ := strconv.ParseBool(c.DefaultQuery("all", "false"))
+
+	report := h.productService.Reconcile(productID)
+
+	mismatches := 0
+	listed := make([]models.StockReconciliation, 0)
+	for _, result := range report {
+		if !result.OK() {
+			mismatches++
+		}
+		if all || !result.OK() {
+			listed = append(listed, result)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": listed,
+		"summary": gin.H{
+			"skus":       len(report),
+			"mismatches": mismatches,
+			"ok":         mismatches == 0,
+		},
+	})
+}
+
+// GET /api/admin/inventory/low-stock?days=30&cover_days=14
+// SKUs at or below their reorder point, emptiest first. Reorder suggestions
+// cover cover_days of sales at the rate of the last days.
+func (h *InventoryHandler) LowStock(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+		return
+	}
+	coverDays, err := strconv.Atoi(c.DefaultQuery("cover_days", "14"))
+	if err != nil || coverDays < 0 || coverDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cover_days must be between 0 and 365"})
+		return
+	}
+
+	items := h.productService.LowStock()
+	sold := h.orderService.UnitsSold(time.Now().AddDate(0, 0, -days))
+	services.SuggestReorder(items, sold, days, coverDays)
+
+	out := 0
+	for _, item := range items {
+		if item.Level == models.StockOut {
+			out++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": items,
+		"summary": gin.H{
+			"low":          len(items) - out,
+			"out_of_stock": out,
+			"days":         days,
+			"cover_days":   coverDays,
+		},
+	})
+}
+
+// GET /api/admin/warehouses
+func (h *InventoryHandler) ListWarehouses(c *gin.Context) {
+	warehouses, strategy := h.productService.Warehouses()
+	c.JSON(http.StatusOK, gin.H{
+		"data":     warehouses,
+		"strategy": strategy,
+	})
+}
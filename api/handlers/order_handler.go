@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
-	// "time"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go-ecommerce/api/middleware"
 	"go-ecommerce/internal/models"
 	"go-ecommerce/internal/services"
 )
@@ -26,7 +29,7 @@ func NewOrderHandler(orderService *services.OrderService, cartService *services.
 
 // POST /api/orders
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
-	userID := h.getCurrentUserID(c) // Pakai method receiver
+	userID := middleware.CurrentUserID(c)
 	
 	var req models.CreateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -45,21 +48,25 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 			req.CartID,
 			userID,
 			req.Address,
-			req.PaymentMethod,
+			req.Payment,
 		)
 	case "batch":
-		order, err = h.orderService.CreateOrderBatchCheck(req.CartID, userID)
+		order, err = h.orderService.CreateOrderBatchCheck(req.CartID, userID, req.Address, req.Payment)
 	default:
 		order, err = h.orderService.CreateOrderSafe(
 			req.CartID,
 			userID,
 			req.Address,
-			req.PaymentMethod,
+			req.Payment,
 		)
 	}
 	
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrCartClosed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 	
@@ -69,9 +76,9 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	})
 }
 
-// POST /api/flash-sale/:product_id/purchase
+// POST /api/flash-sale/:product_id/purchase?quantity=&sku=
 func (h *OrderHandler) FlashSalePurchase(c *gin.Context) {
-	userID := h.getCurrentUserID(c) // Pakai method receiver
+	userID := middleware.CurrentUserID(c)
 	productID, err := strconv.Atoi(c.Param("product_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
@@ -79,8 +86,9 @@ func (h *OrderHandler) FlashSalePurchase(c *gin.Context) {
 	}
 	
 	quantity, _ := strconv.Atoi(c.DefaultQuery("quantity", "1"))
+	item := models.SKURef{ProductID: productID, SKU: c.Query("sku")}
 	
-	order, err := h.orderService.FlashSalePurchase(productID, quantity, userID)
+	order, err := h.orderService.FlashSalePurchase(item, quantity, userID)
 	if err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
@@ -92,7 +100,7 @@ func (h *OrderHandler) FlashSalePurchase(c *gin.Context) {
 	})
 }
 
-// PUT /api/products/:id/stock
+// PUT /api/admin/products/:id/stock
 func (h *OrderHandler) UpdateStock(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -101,7 +109,11 @@ func (h *OrderHandler) UpdateStock(c *gin.Context) {
 	}
 	
 	var req struct {
-		Stock int `json:"stock" binding:"required,gte=0"`
+		Stock         *int   `json:"stock" binding:"required,gte=0"` // a pointer so 0 counts as set
+		SKU           string `json:"sku"` // required for products with variants
+		Location      string `json:"location"` // warehouse code; required for stock tracked per location
+		ExpectedStock *int   `json:"expected_stock"` // compare-and-set: only write if stock is still this
+		Reason        string `json:"reason" binding:"max=200"`
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,27 +121,34 @@ func (h *OrderHandler) UpdateStock(c *gin.Context) {
 		return
 	}
 	
-	product, exists := h.productService.GetProductByID(productID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-		return
+	item := models.SKURef{ProductID: productID, SKU: req.SKU}
+	change := models.StockChange{
+		Type:   models.MovementAdjustment,
+		Actor:  actorOf(c),
+		Reason: req.Reason,
 	}
-	
-	success := h.productService.UpdateStockDirect(productID, req.Stock)
-	if !success {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update stock"})
+	var oldStock int
+	if req.Location != "" {
+		oldStock, err = h.productService.SetLocationStock(item, req.Location, *req.Stock, req.ExpectedStock, change)
+	} else {
+		oldStock, err = h.productService.UpdateStockDirect(item, *req.Stock, req.ExpectedStock, change)
+	}
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Stock updated",
 		"product_id": productID,
-		"old_stock":  product.Stock,
-		"new_stock":  req.Stock,
+		"sku":        req.SKU,
+		"location":   req.Location,
+		"old_stock":  oldStock,
+		"new_stock":  *req.Stock,
 	})
 }
 
-// GET /api/orders/stats
+// GET /api/admin/orders/stats
 func (h *OrderHandler) GetStats(c *gin.Context) {
 	stats := h.orderService.GetStats()
 	
@@ -138,20 +157,195 @@ func (h *OrderHandler) GetStats(c *gin.Context) {
 	})
 }
 
-// Helper function (jadikan method private)
-func (h *OrderHandler) getCurrentUserID(c *gin.Context) int {
-	// Method 1: Dari query parameter
-	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		userID, _ := strconv.Atoi(userIDStr)
-		return userID
+// GET /api/orders/:id
+// Get one of the current user's orders
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	order, err := h.orderService.GetOrder(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
-	
-	// Method 2: Dari header
-	if userIDStr := c.GetHeader("X-User-ID"); userIDStr != "" {
-		userID, _ := strconv.Atoi(userIDStr)
-		return userID
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": order,
+	})
+}
+
+// GET /api/orders?status=&from=&to=&page=&limit=&cursor=
+// List the current user's orders, newest first
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	
-	// Default untuk testing
-	return 1
-}
\ No newline at end of file
+	req, ok := parsePageRequest(c, services.OrderCursorSort)
+	if !ok {
+		return
+	}
+
+	orders, total, next := h.orderService.ListUserOrders(userID, filter, req)
+	c.JSON(http.StatusOK, gin.H{
+		"data": orders,
+		"meta": pageMeta(req, total, next),
+	})
+}
+
+// GET /api/admin/orders?status=&from=&to=&page=&limit=&cursor=
+// List all orders, newest first
+func (h *OrderHandler) ListAllOrders(c *gin.Context) {
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req, ok := parsePageRequest(c, services.OrderCursorSort)
+	if !ok {
+		return
+	}
+
+	orders, total, next := h.orderService.ListOrders(filter, req)
+	c.JSON(http.StatusOK, gin.H{
+		"data": orders,
+		"meta": pageMeta(req, total, next),
+	})
+}
+
+// GET /api/admin/orders/:id
+func (h *OrderHandler) GetAnyOrder(c *gin.Context) {
+	order, exists := h.orderService.GetOrderByID(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": order,
+	})
+}
+
+// Accepts RFC3339 timestamps or plain dates; a plain "to" date includes the whole day
+func parseOrderFilter(c *gin.Context) (models.OrderFilter, error) {
+	var filter models.OrderFilter
+
+	if status := c.Query("status"); status != "" {
+		filter.Status = models.OrderStatus(status)
+		if !filter.Status.IsValid() {
+			return filter, fmt.Errorf("invalid status %q", status)
+		}
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseDateParam(from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date %q", from)
+		}
+		filter.From = t
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseDateParam(to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date %q", to)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = t
+	}
+
+	return filter, nil
+}
+
+func parseDateParam(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	return t, true, err
+}
+
+// POST /api/orders/:id/cancel
+// Cancel own order, stock is returned
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	req, ok := bindTransitionRequest(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.orderService.CancelOrder(c.Param("id"), userID, req.Reason)
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order cancelled",
+		"order":   order,
+	})
+}
+
+// POST /api/orders/:id/process
+func (h *OrderHandler) ProcessOrder(c *gin.Context) {
+	h.transitionOrder(c, models.OrderStatusProcessing)
+}
+
+// POST /api/orders/:id/ship
+func (h *OrderHandler) ShipOrder(c *gin.Context) {
+	h.transitionOrder(c, models.OrderStatusShipped)
+}
+
+// POST /api/orders/:id/deliver
+func (h *OrderHandler) DeliverOrder(c *gin.Context) {
+	h.transitionOrder(c, models.OrderStatusDelivered)
+}
+
+func (h *OrderHandler) transitionOrder(c *gin.Context, status models.OrderStatus) {
+	req, ok := bindTransitionRequest(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.orderService.TransitionOrder(c.Param("id"), status, req.Reason, actorOf(c))
+	if err != nil {
+		c.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order " + string(status),
+		"order":   order,
+	})
+}
+
+// The body is optional for status transitions
+func bindTransitionRequest(c *gin.Context) (models.OrderTransitionRequest, bool) {
+	var req models.OrderTransitionRequest
+	if c.Request.ContentLength == 0 {
+		return req, true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOrderForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrOrderNotReady):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
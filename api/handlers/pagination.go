@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ecommerce/internal/models"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// parsePageRequest reads page/limit and the optional ?cursor= token. A
+// cursor replaces the page number and must come from a list in the same
+// sort. It responds 400 and returns false for a bad cursor.
+func parsePageRequest(c *gin.Context, sort string) (models.PageRequest, bool) {
+	page, limit := parsePagination(c)
+	req := models.PageRequest{Page: page, Limit: limit}
+
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := decodeCursor(token)
+		if err == nil && cursor.Sort != sort {
+			err = errors.New("cursor belongs to a different sort")
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return req, false
+		}
+		req.Page = 1
+		req.After = cursor
+	}
+	return req, true
+}
+
+// encodeCursor turns a cursor into an opaque URL-safe token; nil gives "".
+func encodeCursor(cursor *models.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor models.Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// pageMeta builds the "meta" block of a list response. next_cursor is
+// empty on the last page; page numbers are omitted in cursor mode.
+func pageMeta(req models.PageRequest, total int, next *models.Cursor) gin.H {
+	meta := gin.H{
+		"limit":       req.Limit,
+		"total":       total,
+		"has_next":    next != nil,
+		"next_cursor": encodeCursor(next),
+	}
+	if req.After == nil {
+		meta["page"] = req.Page
+		meta["total_pages"] = (total + req.Limit - 1) / req.Limit
+		meta["has_prev"] = req.Page > 1
+	}
+	return meta
+}
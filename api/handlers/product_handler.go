@@ -1,53 +1,55 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 	"runtime"
 
 	"github.com/gin-gonic/gin"
+	"go-ecommerce/api/middleware"
+	"go-ecommerce/internal/catalog"
+	"go-ecommerce/internal/models"
 	"go-ecommerce/internal/services"
 )
 
 type ProductHandler struct {
-	productService *services.ProductService
+	productService  *services.ProductService
+	categoryService *services.CategoryService
 }
 
-func NewProductHandler(productService *services.ProductService) *ProductHandler {
+func NewProductHandler(productService *services.ProductService, categoryService *services.CategoryService) *ProductHandler {
 	return &ProductHandler{
-		productService: productService,
+		productService:  productService,
+		categoryService: categoryService,
 	}
 }
 
 // Get all products with pagination
+// Pages by ?page= or by the ?cursor= from the previous page's next_cursor
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-
-	if page < 1 {
-		page = 1
+	sortBy, ok := parseProductSort(c)
+	if !ok {
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	req, ok := parsePageRequest(c, string(sortBy))
+	if !ok {
+		return
 	}
 
-	products, total := h.productService.GetAllProducts(page, limit)
+	products, total, next := h.productService.GetAllProducts(req, sortBy)
 
-	totalPages := (total + limit - 1) / limit
-	hasNext := page < totalPages
-	hasPrev := page > 1
+	meta := pageMeta(req, total, next)
+	meta["sort"] = sortBy
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": products,
-		"meta": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": totalPages,
-			"has_next":    hasNext,
-			"has_prev":    hasPrev,
-		},
+		"meta": meta,
 	})
 }
 
@@ -59,7 +61,7 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	product, exists := h.productService.GetProductByID(id)
+	product, exists := h.productService.GetProductWithAvailability(id)
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
@@ -73,39 +75,47 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 // Search products
 func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	query := c.Query("q")
-	category := c.Query("category")
+	// ?category=books&category=home or ?category=books,home; each is an
+	// ID, slug or path and also matches its subcategories
+	categories := queryList(c, "category")
+	var categoryIDs []int
+	for _, ref := range categories {
+		category, exists := h.categoryService.Lookup(ref)
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown category %q", ref)})
+			return
+		}
+		categoryIDs = append(categoryIDs, category.ID)
+	}
 	minPrice, _ := strconv.ParseFloat(c.Query("min_price"), 64)
 	maxPrice, _ := strconv.ParseFloat(c.Query("max_price"), 64)
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-
-	if page < 1 {
-		page = 1
+	sortBy, ok := parseProductSort(c)
+	if !ok {
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	req, ok := parsePageRequest(c, string(sortBy))
+	if !ok {
+		return
 	}
 
-	products, total := h.productService.SearchProducts(
-		query, category, minPrice, maxPrice, page, limit,
-	)
+	products, total, next, facets := h.productService.SearchProducts(models.ProductSearch{
+		Query:      query,
+		Categories: categoryIDs,
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		Sort:       sortBy,
+		Page:       req,
+	})
 
-	totalPages := (total + limit - 1) / limit
-	hasNext := page < totalPages
-	hasPrev := page > 1
+	meta := pageMeta(req, total, next)
+	meta["query"] = query
+	meta["categories"] = categories
+	meta["sort"] = sortBy
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": products,
-		"meta": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": totalPages,
-			"has_next":    hasNext,
-			"has_prev":    hasPrev,
-			"query":       query,
-			"category":    category,
-		},
+		"data":   products,
+		"facets": facets,
+		"meta":   meta,
 	})
 }
 
@@ -118,7 +128,8 @@ func (h *ProductHandler) PurchaseProduct(c *gin.Context) {
 	}
 
 	var req struct {
-		Quantity int `json:"quantity" binding:"required,gt=0"`
+		Quantity int    `json:"quantity" binding:"required,gt=0"`
+		SKU      string `json:"sku"` // required for products with variants
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -126,9 +137,13 @@ func (h *ProductHandler) PurchaseProduct(c *gin.Context) {
 		return
 	}
 
-	success, err := h.productService.UpdateStock(id, req.Quantity)
+	success, _, err := h.productService.UpdateStock(models.SKURef{ProductID: id, SKU: req.SKU}, req.Quantity, models.StockChange{
+		Type:   models.MovementOrder,
+		Actor:  actorOf(c),
+		Reason: "direct purchase",
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -142,10 +157,319 @@ func (h *ProductHandler) PurchaseProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Purchase successful",
 		"product_id": id,
+		"sku":        req.SKU,
 		"quantity":   req.Quantity,
 	})
 }
 
+// POST /api/admin/products
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	var req models.CreateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.CreateProduct(req, actorOf(c))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": product,
+	})
+}
+
+// PATCH /api/admin/products/:id
+// Only non-empty fields are changed
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.UpdateProduct(id, req, actorOf(c))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product updated",
+		"data":    product,
+	})
+}
+
+// DELETE /api/admin/products/:id
+// Soft delete: hidden from the catalog, still visible in order history
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.productService.DeleteProduct(id); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Product deleted",
+		"product_id": id,
+	})
+}
+
+// PUT /api/admin/products/:id/variants/:sku
+// Add or replace one variant
+func (h *ProductHandler) UpsertVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	sku := c.Param("sku")
+	if len(sku) > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SKU is longer than 64 characters"})
+		return
+	}
+
+	var req models.UpsertVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.UpsertVariant(id, sku, req, actorOf(c))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": product,
+	})
+}
+
+// DELETE /api/admin/products/:id/variants/:sku
+func (h *ProductHandler) DeleteVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, err := h.productService.DeleteVariant(id, c.Param("sku"), actorOf(c))
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": product,
+	})
+}
+
+// POST /api/admin/products/import?format=csv|jsonl&dry_run=true
+// Body is the raw file, or multipart form field "file". Any invalid row
+// rejects the whole import; dry_run only reports.
+func (h *ProductHandler) ImportProducts(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var body io.Reader = c.Request.Body
+	formatHint := c.Query("format")
+	if formatHint == "" {
+		formatHint = c.ContentType()
+	}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart upload needs a \"file\" field"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		body = file
+		if c.Query("format") == "" {
+			formatHint = filepath.Ext(fileHeader.Filename)
+		}
+	}
+
+	format, err := catalog.ParseFormat(formatHint)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := catalog.Read(body, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	products, report := catalog.Split(records, format, dryRun)
+	if report.InvalidRows > 0 && !dryRun {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Import rejected: fix the invalid rows or use dry_run=true",
+			"report": report,
+		})
+		return
+	}
+
+	report.Created, report.Updated, err = h.productService.ImportProducts(products, dryRun, actorOf(c))
+	var rejected services.ImportErrors
+	if errors.As(err, &rejected) {
+		for _, rowErr := range rejected {
+			report.Reject(rowErr.Index, rowErr.Err.Error())
+		}
+		if !dryRun {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  "Import rejected: fix the invalid rows or use dry_run=true",
+				"report": report,
+			})
+			return
+		}
+		err = nil
+	}
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// GET /api/admin/products/export?format=csv|jsonl&include_deleted=true
+// Streams the catalog ordered by ID
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	format, err := catalog.ParseFormat(c.DefaultQuery("format", "csv"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	includeDeleted, _ := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+
+	products := h.productService.ExportProducts(includeDeleted)
+
+	contentType := "text/csv; charset=utf-8"
+	if format == catalog.FormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.%s"`, time.Now().Format("20060102"), format))
+	c.Status(http.StatusOK)
+
+	writer, err := catalog.NewWriter(c.Writer, format)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for i := range products {
+		if err := writer.Write(&products[i]); err != nil {
+			c.Error(err) // client went away, headers already sent
+			return
+		}
+		if i%500 == 499 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		c.Error(err)
+	}
+}
+
+const maxImportSize = 32 << 20 // 32 MB
+
+func productErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrSKUNotFound),
+		errors.Is(err, services.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSKURequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrZeroDelta),
+		errors.Is(err, services.ErrUnknownLocation), errors.Is(err, services.ErrLocationRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInvalidStockPolicy), errors.Is(err, services.ErrReleaseDateRequired):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrInsufficientStock), errors.Is(err, services.ErrInvalidQuantity),
+		errors.Is(err, services.ErrDuplicateSKU), errors.Is(err, services.ErrStockMismatch),
+		errors.Is(err, services.ErrNegativeStock):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// actorOf names the caller for the inventory ledger. Some routes (direct
+// purchase) don't require a login.
+func actorOf(c *gin.Context) string {
+	if userID := middleware.CurrentUserID(c); userID != 0 {
+		return models.UserActor(userID)
+	}
+	return "anonymous"
+}
+
+// page & limit query params, same defaults for every list endpoint
+func parsePagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// parseProductSort reads ?sort=. It responds 400 and returns false for an
+// unknown sort.
+func parseProductSort(c *gin.Context) (models.ProductSort, bool) {
+	sortBy := models.ProductSort(c.Query("sort"))
+	if !sortBy.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid sort %q: use relevance, price_asc, price_desc, newest, name or stock", sortBy),
+		})
+		return "", false
+	}
+	return sortBy, true
+}
+
+// queryList reads a multi-valued query parameter given either repeated
+// or comma-separated.
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
 // Health check endpoint
 func (h *ProductHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
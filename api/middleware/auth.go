@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-ecommerce/internal/auth"
+	"go-ecommerce/internal/models"
+)
+
+const identityKey = "auth.identity"
+
+// UserRoles resolves a user's current role; *services.UserService satisfies it.
+type UserRoles interface {
+	RoleOf(userID int) (models.Role, bool)
+}
+
+// Auth requires a valid "Authorization: Bearer <token>" header and stores
+// the caller's identity, including their current role, in the context.
+//
+// With devMode on, requests without a bearer token may instead name the
+// user via ?user_id= or the X-User-ID header. Only meant for local load
+// testing; never enable it in production.
+func Auth(tokens *auth.TokenManager, devMode bool, users UserRoles) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var userID int
+
+		if header := c.GetHeader("Authorization"); header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				unauthorized(c, "Authorization header must use the Bearer scheme")
+				return
+			}
+
+			identity, err := tokens.Verify(strings.TrimSpace(token))
+			if err != nil {
+				if errors.Is(err, auth.ErrTokenExpired) {
+					unauthorized(c, "Token expired")
+				} else {
+					unauthorized(c, "Invalid token")
+				}
+				return
+			}
+			userID = identity.UserID
+		} else if devMode {
+			userIDStr := c.Query("user_id")
+			if userIDStr == "" {
+				userIDStr = c.GetHeader("X-User-ID")
+			}
+			if userIDStr == "" {
+				unauthorized(c, "Authentication required")
+				return
+			}
+
+			id, err := strconv.Atoi(userIDStr)
+			if err != nil || id <= 0 {
+				unauthorized(c, "Invalid user ID")
+				return
+			}
+			userID = id
+		} else {
+			unauthorized(c, "Authentication required")
+			return
+		}
+
+		role, exists := users.RoleOf(userID)
+		if !exists {
+			unauthorized(c, "Unknown user")
+			return
+		}
+
+		c.Set(identityKey, auth.Identity{UserID: userID, Role: role})
+		c.Next()
+	}
+}
+
+// RequireRole lets the request through only if the caller has one of the
+// given roles. Must run after Auth.
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := CurrentIdentity(c)
+		if !ok {
+			unauthorized(c, "Authentication required")
+			return
+		}
+
+		for _, role := range roles {
+			if identity.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
+
+// CurrentIdentity returns the identity set by Auth.
+func CurrentIdentity(c *gin.Context) (auth.Identity, bool) {
+	value, exists := c.Get(identityKey)
+	if !exists {
+		return auth.Identity{}, false
+	}
+	identity, ok := value.(auth.Identity)
+	return identity, ok
+}
+
+// CurrentUserID returns the authenticated user's ID. Only call it from
+// handlers behind Auth.
+func CurrentUserID(c *gin.Context) int {
+	identity, _ := CurrentIdentity(c)
+	return identity.UserID
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
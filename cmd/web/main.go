@@ -2,33 +2,181 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go-ecommerce/api/handlers"
+	"go-ecommerce/api/middleware"
+	"go-ecommerce/internal/auth"
+	"go-ecommerce/internal/catalog"
+	"go-ecommerce/internal/media"
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
 	"go-ecommerce/internal/services"
 )
 
 func main() {
+	// Subcommand: import a catalog file into the store and exit
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+	
+	catalogFile := flag.String("catalog", "", "seed an empty store from this CSV/JSONL file instead of sample data")
+	flag.Parse()
+	
+	// Initialize storage: STORAGE_DRIVER=memory (default) or file
+	store, err := repository.Open(os.Getenv("STORAGE_DRIVER"), getEnv("DATA_DIR", "data"))
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+	
+	// Authentication: AUTH_SECRET signs bearer tokens. AUTH_DEV_MODE=true also
+	// accepts ?user_id= / X-User-ID for load testing.
+	devMode := os.Getenv("AUTH_DEV_MODE") == "true"
+	secret := os.Getenv("AUTH_SECRET")
+	if secret == "" {
+		if !devMode {
+			log.Fatal("AUTH_SECRET is required (or set AUTH_DEV_MODE=true for local testing)")
+		}
+		secret = "dev-secret-do-not-use-in-production"
+	}
+	tokenTTL, err := time.ParseDuration(getEnv("AUTH_TOKEN_TTL", "24h"))
+	if err != nil {
+		log.Fatalf("Invalid AUTH_TOKEN_TTL: %v", err)
+	}
+	tokens := auth.NewTokenManager([]byte(secret), tokenTTL)
+	
 	// Initialize services
-	productService := services.NewProductService()
-	productService.InitSampleData()
+	userService := services.NewUserService(store.Users, tokens)
+	if devMode {
+		log.Println("⚠️  AUTH_DEV_MODE enabled: user_id query/header is trusted")
+		// Load test scripts act as users up to ~2100
+		if err := userService.InitSampleUsers(2500); err != nil {
+			log.Fatalf("Failed to seed sample users: %v", err)
+		}
+	} else if userService.SampleStaffActive() {
+		// Left behind by a dev mode run on the same data
+		log.Fatalf("Sample admin/staff accounts still use the password %q; change their passwords in dev mode or remove them from the store", services.SampleUserPassword)
+	}
+	
+	// ADMIN_EMAIL/ADMIN_PASSWORD bootstrap the first admin account
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		if err := userService.EnsureAdmin(adminEmail, os.Getenv("ADMIN_PASSWORD")); err != nil {
+			log.Fatalf("Failed to create admin account: %v", err)
+		}
+	}
 	
-	cartService := services.NewCartService()
-	orderService := services.NewOrderService(productService, cartService)
+	categoryService := services.NewCategoryService(store.Categories)
+	productService := services.NewProductService(store.Products, categoryService, store.Ledger)
+	if linked, err := productService.LinkCategories(); err != nil {
+		log.Fatalf("Failed to link product categories: %v", err)
+	} else if linked > 0 {
+		log.Printf("🗂️  Linked %d products to categories", linked)
+	}
+	if opened, err := productService.OpenLedger(); err != nil {
+		log.Fatalf("Failed to open inventory ledger: %v", err)
+	} else if opened > 0 {
+		log.Printf("📒 Recorded opening stock for %d SKUs", opened)
+	}
+	if *catalogFile != "" {
+		if productService.Count() == 0 {
+			report, err := importCatalogFile(productService, *catalogFile, "", false, models.ActorSystem)
+			if err != nil {
+				log.Fatalf("Failed to seed catalog: %v", err)
+			}
+			log.Printf("📦 Seeded %d products from %s", report.Created+report.Updated, *catalogFile)
+		} else {
+			log.Printf("Store already has products, ignoring -catalog %s", *catalogFile)
+		}
+	} else if err := productService.InitSampleData(); err != nil {
+		log.Fatalf("Failed to seed sample data: %v", err)
+	}
+	
+	// Items in carts hold stock for CART_HOLD_TTL after the last cart change
+	holdTTL, err := time.ParseDuration(getEnv("CART_HOLD_TTL", services.DefaultHoldTTL.String()))
+	if err != nil || holdTTL <= 0 {
+		log.Fatalf("Invalid CART_HOLD_TTL: %q", os.Getenv("CART_HOLD_TTL"))
+	}
+	productService.SetHoldTTL(holdTTL)
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	productService.StartHoldSweeper(backgroundCtx, max(min(holdTTL/10, time.Minute), time.Second))
+	
+	// Low-stock monitor: SKUs at or below their reorder point (REORDER_POINT
+	// for products without one) are logged once per drop
+	reorderPoint, err := strconv.Atoi(getEnv("REORDER_POINT", strconv.Itoa(services.DefaultReorderPoint)))
+	if err != nil || reorderPoint < 0 {
+		log.Fatalf("Invalid REORDER_POINT: %q", os.Getenv("REORDER_POINT"))
+	}
+	monitorInterval, err := time.ParseDuration(getEnv("STOCK_MONITOR_INTERVAL", "1m"))
+	if err != nil || monitorInterval <= 0 {
+		log.Fatalf("Invalid STOCK_MONITOR_INTERVAL: %q", os.Getenv("STOCK_MONITOR_INTERVAL"))
+	}
+	// Warehouses: WAREHOUSES_FILE is a JSON array of models.Warehouse;
+	// ALLOCATION_STRATEGY picks where order lines ship from
+	if err := loadWarehouses(productService, os.Getenv("WAREHOUSES_FILE"), getEnv("ALLOCATION_STRATEGY", string(services.DefaultAllocationStrategy))); err != nil {
+		log.Fatalf("Failed to load warehouses: %v", err)
+	}
+	
+	productService.SetReorderPoint(reorderPoint)
+	productService.OnStockAlert(logStockAlert)
+	productService.StartStockMonitor(backgroundCtx, monitorInterval)
+	
+	// Product images: MEDIA_DIR on local disk, served under /media
+	mediaStorage, err := media.NewLocalStorage(getEnv("MEDIA_DIR", filepath.Join(getEnv("DATA_DIR", "data"), "media")), "/media")
+	if err != nil {
+		log.Fatalf("Failed to open media storage: %v", err)
+	}
+	imageService := services.NewImageService(productService, mediaStorage)
+	
+	// Carts idle for CART_TTL are abandoned, releasing their holds; closed
+	// carts are deleted after CART_RETENTION
+	cartTTL, err := time.ParseDuration(getEnv("CART_TTL", services.DefaultCartTTL.String()))
+	if err != nil || cartTTL <= 0 {
+		log.Fatalf("Invalid CART_TTL: %q", os.Getenv("CART_TTL"))
+	}
+	cartRetention, err := time.ParseDuration(getEnv("CART_RETENTION", services.DefaultCartRetention.String()))
+	if err != nil || cartRetention <= 0 {
+		log.Fatalf("Invalid CART_RETENTION: %q", os.Getenv("CART_RETENTION"))
+	}
+	cartService := services.NewCartService(store.Carts, userService)
+	cartService.SetCartExpiry(cartTTL, cartRetention)
+	cartService.OnCartAbandoned(func(cart *models.Cart) {
+		productService.ReleaseHolds(cart.ID)
+	})
+	cartService.StartCartJanitor(backgroundCtx, max(min(cartTTL/10, time.Hour), time.Second))
+	orderService := services.NewOrderService(store.Orders, productService, cartService, userService)
+	// Restocks go to backordered order lines right away; the interval
+	// catches stock freed by expired cart holds
+	orderService.StartBackorderAllocator(backgroundCtx, time.Minute)
 	
 	// Initialize handlers
-	productHandler := handlers.NewProductHandler(productService)
+	productHandler := handlers.NewProductHandler(productService, categoryService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, productService)
+	imageHandler := handlers.NewImageHandler(imageService, productService)
+	inventoryHandler := handlers.NewInventoryHandler(productService, orderService)
 	cartHandler := handlers.NewCartHandler(cartService, productService)
 	orderHandler := handlers.NewOrderHandler(orderService, cartService, productService)
+	userHandler := handlers.NewUserHandler(userService)
 	
 	// Setup router
-	router := setupRouter(productHandler, cartHandler, orderHandler)
+	router := setupRouter(productHandler, categoryHandler, imageHandler, inventoryHandler, cartHandler, orderHandler, userHandler, middleware.Auth(tokens, devMode, userService))
+	router.Static("/media", mediaStorage.Dir())
 	
 	// Start server
 	server := &http.Server{
@@ -58,13 +206,47 @@ func main() {
 	defer cancel()
 	
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		log.Printf("Server forced to shutdown: %v", err)
 	}
 	
 	log.Println("✅ Server shutdown complete")
 }
 
-func setupRouter(productHandler *handlers.ProductHandler, cartHandler *handlers.CartHandler, orderHandler *handlers.OrderHandler) *gin.Engine {
+func loadWarehouses(productService *services.ProductService, path, strategy string) error {
+	var warehouses []models.Warehouse
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &warehouses); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		log.Printf("🏭 Loaded %d warehouses, allocating by %s", len(warehouses), strategy)
+	}
+	return productService.SetWarehouses(warehouses, models.AllocationStrategy(strategy))
+}
+
+func logStockAlert(alert models.StockAlert) {
+	sku := ""
+	if alert.SKU != "" {
+		sku = " sku " + alert.SKU
+	}
+	if alert.Level == models.StockOut {
+		log.Printf("🚨 Out of stock: %s (product %d%s)", alert.Name, alert.ProductID, sku)
+		return
+	}
+	log.Printf("⚠️  Low stock: %s (product %d%s) %d left, reorder point %d", alert.Name, alert.ProductID, sku, alert.Stock, alert.ReorderPoint)
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func setupRouter(productHandler *handlers.ProductHandler, categoryHandler *handlers.CategoryHandler, imageHandler *handlers.ImageHandler, inventoryHandler *handlers.InventoryHandler, cartHandler *handlers.CartHandler, orderHandler *handlers.OrderHandler, userHandler *handlers.UserHandler, requireAuth gin.HandlerFunc) *gin.Engine {
 	if os.Getenv("ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -83,27 +265,84 @@ func setupRouter(productHandler *handlers.ProductHandler, cartHandler *handlers.
 			products.GET("/:id", productHandler.GetProductByID)
 			products.GET("/search", productHandler.SearchProducts)
 			products.POST("/:id/purchase", productHandler.PurchaseProduct)
-			products.PUT("/:id/stock", orderHandler.UpdateStock)
+		}
+		
+		// Category routes
+		categories := api.Group("/categories")
+		{
+			categories.GET("/", categoryHandler.GetTree)
+			categories.GET("/:slug", categoryHandler.GetCategory)
+		}
+		
+		// User routes
+		users := api.Group("/users")
+		{
+			users.POST("/register", userHandler.Register)
+			users.POST("/login", userHandler.Login)
+			users.GET("/me", requireAuth, userHandler.GetProfile)
+			users.PATCH("/me", requireAuth, userHandler.UpdateProfile)
+			users.PUT("/me/password", requireAuth, userHandler.ChangePassword)
 		}
 		
 		// Cart routes
-		cart := api.Group("/cart")
+		cart := api.Group("/cart", requireAuth)
 		{
 			cart.POST("/", cartHandler.CreateCart)
 			cart.GET("/", cartHandler.GetCart)
 			cart.POST("/items", cartHandler.AddToCart)
 			cart.PUT("/items/:product_id", cartHandler.UpdateCartItem)
+			cart.DELETE("/items/:product_id", cartHandler.RemoveCartItem)
+			cart.DELETE("/", cartHandler.ClearCart)
 		}
 		
 		// Order routes
-		orders := api.Group("/orders")
+		orders := api.Group("/orders", requireAuth)
 		{
 			orders.POST("/", orderHandler.CreateOrder)
-			orders.GET("/stats", orderHandler.GetStats)
+			orders.GET("/", orderHandler.ListOrders)
+			orders.GET("/:id", orderHandler.GetOrder)
+			orders.POST("/:id/cancel", orderHandler.CancelOrder)
+			
+			// Fulfilment is done by staff
+			fulfilment := orders.Group("", middleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+			fulfilment.POST("/:id/process", orderHandler.ProcessOrder)
+			fulfilment.POST("/:id/ship", orderHandler.ShipOrder)
+			fulfilment.POST("/:id/deliver", orderHandler.DeliverOrder)
+		}
+		
+		// Admin routes
+		admin := api.Group("/admin", requireAuth, middleware.RequireRole(models.RoleAdmin))
+		{
+			admin.POST("/products", productHandler.CreateProduct)
+			admin.POST("/products/import", productHandler.ImportProducts)
+			admin.GET("/products/export", productHandler.ExportProducts)
+			admin.PATCH("/products/:id", productHandler.UpdateProduct)
+			admin.DELETE("/products/:id", productHandler.DeleteProduct)
+			admin.PUT("/products/:id/variants/:sku", productHandler.UpsertVariant)
+			admin.DELETE("/products/:id/variants/:sku", productHandler.DeleteVariant)
+			admin.POST("/products/:id/images", imageHandler.UploadImage)
+			admin.PUT("/products/:id/images/order", imageHandler.ReorderImages)
+			admin.PATCH("/products/:id/images/:image_id", imageHandler.UpdateImage)
+			admin.DELETE("/products/:id/images/:image_id", imageHandler.DeleteImage)
+			admin.PUT("/products/:id/stock", orderHandler.UpdateStock)
+			admin.POST("/products/:id/stock/adjust", inventoryHandler.AdjustStock)
+			admin.GET("/products/:id/stock-history", inventoryHandler.StockHistory)
+			admin.POST("/inventory/adjust", inventoryHandler.BulkAdjustStock)
+			admin.GET("/inventory/low-stock", inventoryHandler.LowStock)
+			admin.GET("/warehouses", inventoryHandler.ListWarehouses)
+			admin.GET("/inventory/reconcile", inventoryHandler.Reconcile)
+			admin.POST("/categories", categoryHandler.CreateCategory)
+			admin.PATCH("/categories/:id", categoryHandler.UpdateCategory)
+			admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+			admin.GET("/carts/abandoned", cartHandler.AbandonedCarts)
+			admin.GET("/orders", orderHandler.ListAllOrders)
+			admin.GET("/orders/stats", orderHandler.GetStats)
+			admin.GET("/orders/:id", orderHandler.GetAnyOrder)
+			admin.PUT("/users/:id/role", userHandler.UpdateRole)
 		}
 		
 		// Flash sale
-		api.POST("/flash-sale/:product_id/purchase", orderHandler.FlashSalePurchase)
+		api.POST("/flash-sale/:product_id/purchase", requireAuth, orderHandler.FlashSalePurchase)
 		
 		// Health check
 		api.GET("/health", productHandler.HealthCheck)
@@ -111,8 +350,88 @@ func setupRouter(productHandler *handlers.ProductHandler, cartHandler *handlers.
 	
 	// Debug endpoints in development
 	if gin.Mode() != gin.ReleaseMode {
-		router.GET("/debug/metrics", productHandler.Metrics)
+		router.GET("/debug/metrics", requireAuth, middleware.RequireRole(models.RoleAdmin), productHandler.Metrics)
 	}
 	
 	return router
-}
\ No newline at end of file
+}
+
+// runImport implements the "import" subcommand:
+//
+//	go run cmd/web/main.go import [-dry-run] [-format csv|jsonl] catalog.csv
+//
+// It loads the file into the configured store (use STORAGE_DRIVER=file so
+// the result survives) and prints the report as JSON.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing")
+	format := fs.String("format", "", "csv or jsonl (default: from file extension)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import [-dry-run] [-format csv|jsonl] FILE")
+	}
+
+	store, err := repository.Open(os.Getenv("STORAGE_DRIVER"), getEnv("DATA_DIR", "data"))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	productService := services.NewProductService(store.Products, services.NewCategoryService(store.Categories), store.Ledger)
+	if _, err := productService.LinkCategories(); err != nil {
+		return err
+	}
+	if _, err := productService.OpenLedger(); err != nil {
+		return err
+	}
+	report, err := importCatalogFile(productService, fs.Arg(0), *format, *dryRun, "cli")
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	return err
+}
+
+// importCatalogFile imports a CSV or JSONL file. Any invalid row rejects
+// the whole file unless dryRun is set. actor is recorded in the inventory
+// ledger.
+func importCatalogFile(productService *services.ProductService, path, format string, dryRun bool, actor string) (*catalog.Report, error) {
+	if format == "" {
+		format = filepath.Ext(path)
+	}
+	parsedFormat, err := catalog.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := catalog.Read(file, parsedFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	products, report := catalog.Split(records, parsedFormat, dryRun)
+	if report.InvalidRows > 0 && !dryRun {
+		return report, fmt.Errorf("%s: %d invalid rows, nothing imported", path, report.InvalidRows)
+	}
+
+	report.Created, report.Updated, err = productService.ImportProducts(products, dryRun, actor)
+	var rejected services.ImportErrors
+	if errors.As(err, &rejected) {
+		for _, rowErr := range rejected {
+			report.Reject(rowErr.Index, rowErr.Err.Error())
+		}
+		if dryRun {
+			return report, nil
+		}
+		return report, fmt.Errorf("%s: %d invalid rows, nothing imported", path, report.InvalidRows)
+	}
+	return report, err
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Identity is the authenticated caller attached to each request. Tokens
+// only carry the user ID; Role is looked up per request so role changes
+// apply immediately.
+type Identity struct {
+	UserID int
+	Role   models.Role
+}
+
+// Claims is the JWT payload we issue and accept.
+type Claims struct {
+	Subject   string `json:"sub"` // user ID
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// TokenManager issues and verifies HS256 JSON Web Tokens signed with a
+// locally configured key.
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenManager(secret []byte, ttl time.Duration) *TokenManager {
+	return &TokenManager{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Issue returns a signed token for the identity and its expiry time.
+func (m *TokenManager) Issue(identity Identity) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	header, err := json.Marshal(tokenHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload, err := json.Marshal(Claims{
+		Subject:   strconv.Itoa(identity.UserID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	return signingInput + "." + encodeSegment(m.sign(signingInput)), expiresAt, nil
+}
+
+// Verify checks the signature and expiry and returns the token's identity.
+func (m *TokenManager) Verify(token string) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, ErrInvalidToken
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, m.sign(parts[0]+"."+parts[1])) {
+		return Identity{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return Identity{}, ErrTokenExpired
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return Identity{}, ErrInvalidToken
+	}
+
+	return Identity{UserID: userID}, nil
+}
+
+func (m *TokenManager) sign(signingInput string) []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,385 @@
+// Package catalog reads and writes product catalogs as CSV or JSON Lines.
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"go-ecommerce/internal/models"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// Columns is the CSV header written by export and expected by import.
+// Only the columns in optionalColumns may be left out. CSV carries no variants: a product with
+// variants exports its derived price and stock, and importing the row
+// keeps the variants it already has.
+var Columns = []string{"id", "name", "description", "price", "stock", "category", "reorder_point",
+	"stock_policy", "backorder_limit", "release_date"}
+
+// optionalColumns may be missing from an imported CSV header. Settings
+// left empty keep the product's current value; backorder_limit and
+// release_date only count together with stock_policy.
+var optionalColumns = map[string]bool{
+	"id":              true,
+	"reorder_point":   true,
+	"stock_policy":    true,
+	"backorder_limit": true,
+	"release_date":    true,
+}
+
+// ParseFormat accepts a format name, file extension or content type.
+func ParseFormat(value string) (Format, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	switch {
+	case value == "csv", value == ".csv", value == "text/csv":
+		return FormatCSV, nil
+	case value == "jsonl", value == ".jsonl", value == "ndjson", value == ".ndjson",
+		value == "application/x-ndjson", value == "application/jsonl", value == "application/x-jsonlines":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unsupported catalog format %q (use csv or jsonl)", value)
+}
+
+// Record is one parsed row. Errors holds parse and validation problems;
+// a record with errors must not be imported.
+type Record struct {
+	Line    int                  `json:"line"`
+	Product models.ProductImport `json:"-"`
+	Errors  []string             `json:"errors,omitempty"`
+
+	malformed bool // row could not be decoded at all, skip validation
+}
+
+// Report summarises an import or dry run.
+type Report struct {
+	Format      Format   `json:"format"`
+	DryRun      bool     `json:"dry_run"`
+	TotalRows   int      `json:"total_rows"`
+	ValidRows   int      `json:"valid_rows"`
+	InvalidRows int      `json:"invalid_rows"`
+	Created     int      `json:"created"` // would be created, for a dry run
+	Updated     int      `json:"updated"`
+	Errors      []Record `json:"errors,omitempty"`
+
+	lines []int // line of each valid row, in the order Split returned them
+}
+
+// Split returns the products from valid records and a report listing the
+// invalid ones.
+func Split(records []Record, format Format, dryRun bool) ([]models.ProductImport, *Report) {
+	report := &Report{
+		Format:    format,
+		DryRun:    dryRun,
+		TotalRows: len(records),
+	}
+
+	products := make([]models.ProductImport, 0, len(records))
+	for _, record := range records {
+		if len(record.Errors) > 0 {
+			report.Errors = append(report.Errors, record)
+			continue
+		}
+		products = append(products, record.Product)
+		report.lines = append(report.lines, record.Line)
+	}
+	report.ValidRows = len(products)
+	report.InvalidRows = len(report.Errors)
+	return products, report
+}
+
+// Reject moves a valid row to the errors, e.g. when the import finds a
+// problem the row itself can't show. index is the row's position in the
+// products Split returned.
+func (r *Report) Reject(index int, message string) {
+	if index < 0 || index >= len(r.lines) {
+		return
+	}
+	r.Errors = append(r.Errors, Record{Line: r.lines[index], Errors: []string{message}})
+	sort.SliceStable(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Line < r.Errors[j].Line
+	})
+	r.ValidRows--
+	r.InvalidRows++
+}
+
+// Read parses every row and validates it against the CreateProductRequest
+// rules. Only a malformed file (e.g. bad CSV header) returns an error;
+// row problems are reported on each Record.
+func Read(r io.Reader, format Format) ([]Record, error) {
+	var records []Record
+	var err error
+
+	switch format {
+	case FormatCSV:
+		records, err = readCSV(r)
+	case FormatJSONL:
+		records, err = readJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported catalog format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].malformed {
+			continue
+		}
+		records[i].Errors = append(records[i].Errors, validate(records[i].Product)...)
+	}
+	return records, nil
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range Columns {
+		if _, ok := index[name]; !ok && !optionalColumns[name] {
+			return nil, fmt.Errorf("csv header is missing column %q", name)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, Record{Line: parseErr.Line, Errors: []string{parseErr.Err.Error()}, malformed: true})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		record := Record{Line: line}
+		p := &record.Product
+
+		p.Name = field("name")
+		p.Description = field("description")
+		p.Category = field("category")
+
+		if v := field("id"); v != "" {
+			if p.ID, err = strconv.Atoi(v); err != nil || p.ID < 0 {
+				record.Errors = append(record.Errors, fmt.Sprintf("id: invalid integer %q", v))
+			}
+		}
+		if v := field("price"); v != "" {
+			if p.Price, err = strconv.ParseFloat(v, 64); err != nil {
+				record.Errors = append(record.Errors, fmt.Sprintf("price: invalid number %q", v))
+			}
+		}
+		if v := field("stock"); v != "" {
+			if p.Stock, err = strconv.Atoi(v); err != nil {
+				record.Errors = append(record.Errors, fmt.Sprintf("stock: invalid integer %q", v))
+			}
+		}
+		if v := field("reorder_point"); v != "" {
+			reorderPoint, err := strconv.Atoi(v)
+			if err != nil {
+				record.Errors = append(record.Errors, fmt.Sprintf("reorder_point: invalid integer %q", v))
+			}
+			p.ReorderPoint = &reorderPoint
+		}
+		p.StockPolicy = models.StockPolicy(field("stock_policy"))
+		if v := field("backorder_limit"); v != "" {
+			if p.BackorderLimit, err = strconv.Atoi(v); err != nil {
+				record.Errors = append(record.Errors, fmt.Sprintf("backorder_limit: invalid integer %q", v))
+			}
+		}
+		if v := field("release_date"); v != "" {
+			releaseDate, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				record.Errors = append(record.Errors, fmt.Sprintf("release_date: invalid RFC 3339 time %q", v))
+			}
+			p.ReleaseDate = &releaseDate
+		}
+
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func readJSONL(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var records []Record
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		record := Record{Line: line}
+		if err := json.Unmarshal([]byte(text), &record.Product); err != nil {
+			record.Errors = []string{err.Error()}
+			record.malformed = true
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// validate applies the same binding rules the HTTP API uses, except that
+// rows of products taking backorders may have negative stock: backorders
+// take stock below zero and export writes it as it is.
+func validate(product models.ProductImport) []string {
+	var errs []string
+	if product.ID < 0 {
+		errs = append(errs, "id: must not be negative")
+	}
+	request := product.CreateProductRequest
+	if request.StockPolicy.AllowsBackorders() {
+		request.Stock = max(request.Stock, 0)
+		request.Variants = append([]models.VariantRequest(nil), request.Variants...)
+		for i := range request.Variants {
+			request.Variants[i].Stock = max(request.Variants[i].Stock, 0)
+		}
+	}
+	if err := binding.Validator.ValidateStruct(&request); err != nil {
+		errs = append(errs, strings.Split(err.Error(), "\n")...)
+	}
+	return errs
+}
+
+// ============================================
+// EXPORT
+// ============================================
+
+// Writer streams products in one format. Call Flush when done.
+type Writer interface {
+	Write(product *models.Product) error
+	Flush() error
+}
+
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := &csvWriter{w: csv.NewWriter(w)}
+		if err := cw.w.Write(Columns); err != nil {
+			return nil, err
+		}
+		return cw, nil
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlWriter{buf: bw, enc: json.NewEncoder(bw)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported catalog format %q", format)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) Write(p *models.Product) error {
+	releaseDate := ""
+	if p.ReleaseDate != nil {
+		releaseDate = p.ReleaseDate.Format(time.RFC3339)
+	}
+	return cw.w.Write([]string{
+		strconv.Itoa(p.ID),
+		p.Name,
+		p.Description,
+		strconv.FormatFloat(p.Price, 'f', -1, 64),
+		strconv.Itoa(p.Stock),
+		p.Category,
+		strconv.Itoa(p.ReorderPoint),
+		string(p.StockPolicy),
+		strconv.Itoa(p.BackorderLimit),
+		releaseDate,
+	})
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (jw *jsonlWriter) Write(p *models.Product) error {
+	reorderPoint := p.ReorderPoint
+	item := models.ProductImport{
+		ID: p.ID,
+		CreateProductRequest: models.CreateProductRequest{
+			Name:         p.Name,
+			Description:  p.Description,
+			Price:        p.Price,
+			Stock:        p.Stock,
+			Category:     p.Category,
+			ReorderPoint: &reorderPoint,
+
+			StockPolicy:    p.StockPolicy,
+			BackorderLimit: p.BackorderLimit,
+			ReleaseDate:    p.ReleaseDate,
+		},
+	}
+	for _, v := range p.Variants {
+		item.Variants = append(item.Variants, models.VariantRequest{
+			SKU:     v.SKU,
+			Options: v.Options,
+			Price:   v.Price,
+			Stock:   v.Stock,
+		})
+	}
+	return jw.enc.Encode(item)
+}
+
+func (jw *jsonlWriter) Flush() error {
+	return jw.buf.Flush()
+}
@@ -0,0 +1,82 @@
+package catalog
+
+import (
+	"bytes"
+	"testing"
+
+	"go-ecommerce/internal/models"
+)
+
+func TestRoundTripBackorderedProduct(t *testing.T) {
+	products := []models.Product{
+		{
+			ID:             1,
+			Name:           "Backordered lamp",
+			Description:    "Sold out, more on the way",
+			Price:          25,
+			Stock:          -3,
+			Category:       "Home",
+			StockPolicy:    models.PolicyBackorder,
+			BackorderLimit: 5,
+		},
+		{
+			ID:          2,
+			Name:        "Backordered shirt",
+			Description: "Sold out in one size",
+			Price:       15,
+			Stock:       2,
+			Category:    "Clothing",
+			StockPolicy: models.PolicyBackorder,
+			Variants: []models.Variant{
+				{SKU: "SHIRT-M", Price: 15, Stock: -2},
+				{SKU: "SHIRT-L", Price: 15, Stock: 4},
+			},
+		},
+	}
+
+	for _, format := range []Format{FormatCSV, FormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			for i := range products {
+				if err := writer.Write(&products[i]); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			records, err := Read(&buf, format)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			items, report := Split(records, format, false)
+			if report.InvalidRows != 0 {
+				t.Fatalf("re-import rejected rows: %+v", report.Errors)
+			}
+			if len(items) != len(products) {
+				t.Fatalf("read %d rows, want %d", len(items), len(products))
+			}
+			for i, item := range items {
+				if item.Stock != products[i].Stock || item.StockPolicy != products[i].StockPolicy {
+					t.Errorf("row %d: stock %d policy %q, want %d %q", i+1, item.Stock, item.StockPolicy, products[i].Stock, products[i].StockPolicy)
+				}
+			}
+		})
+	}
+}
+
+func TestNegativeStockNeedsBackorders(t *testing.T) {
+	input := "id,name,description,price,stock,category\n1,Plain lamp,Never sold short,25,-3,Home\n"
+	records, err := Read(bytes.NewBufferString(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(records) != 1 || len(records[0].Errors) == 0 {
+		t.Errorf("negative stock without a backorder policy was accepted: %+v", records)
+	}
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers GIF with image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+var ErrUnsupportedImage = errors.New("unsupported image: use JPEG, PNG or GIF")
+
+// MaxPixels bounds decoded image size so a small file with huge
+// dimensions cannot exhaust memory.
+const MaxPixels = 40_000_000
+
+// Decode reads a JPEG, PNG or GIF and returns it with its format name
+// ("jpeg", "png" or "gif").
+func Decode(data []byte) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d is too large", ErrUnsupportedImage, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	return img, format, nil
+}
+
+func ContentType(format string) string {
+	switch format {
+	case "png":
+		return "image/png"
+	case "gif":
+		return "image/gif"
+	default:
+		return "image/jpeg"
+	}
+}
+
+func Extension(format string) string {
+	switch format {
+	case "png":
+		return ".png"
+	case "gif":
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
+
+// EncodeThumbnail writes a thumbnail in the format that suits the
+// original: JPEG stays JPEG, PNG and GIF become PNG to keep transparency.
+// It returns the format written.
+func EncodeThumbnail(w io.Writer, img image.Image, format string) (string, error) {
+	if format == "jpeg" {
+		return "jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return "png", png.Encode(w, img)
+}
+
+// Thumbnail scales src down to fit within size x size, keeping its aspect
+// ratio. Each output pixel is the average of the source pixels it covers.
+// Images that already fit are returned unchanged.
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	// Work on premultiplied RGBA so averaging handles transparency
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, max((ty+1)*h/th, ty*h/th+1)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, max((tx+1)*w/tw, tx*w/tw+1)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				row := rgba.Pix[y*rgba.Stride+x0*4 : y*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+
+			i := ty*dst.Stride + tx*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,108 @@
+// Package media stores uploaded files such as product images and makes
+// thumbnails for them.
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid media key")
+
+// Storage keeps files under slash-separated keys like
+// "products/12/3f9c.jpg". LocalStorage is the only implementation today;
+// an object store (S3, GCS) only needs the same four methods.
+type Storage interface {
+	Put(key string, r io.Reader, contentType string) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// URL is where clients download the file from.
+	URL(key string) string
+}
+
+// LocalStorage keeps files in a directory. Serve that directory at
+// baseURL (e.g. router.Static("/media", dir)) so the URLs resolve.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+func (s *LocalStorage) Dir() string {
+	return s.dir
+}
+
+// Put writes to a temporary file first so a failed upload never leaves a
+// half-written file behind.
+func (s *LocalStorage) Put(key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(name)
+}
+
+// Delete removes a file; a missing file is not an error.
+func (s *LocalStorage) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// path maps a key into the storage directory, rejecting keys that would
+// escape it.
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != key {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
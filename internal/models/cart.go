@@ -9,21 +9,68 @@ type Cart struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	Version   int            `json:"-"` // Optimistic locking
+	Status    CartStatus     `json:"status"`
+	OrderID   string         `json:"order_id,omitempty"` // set once converted
+	ClosedAt  *time.Time     `json:"closed_at,omitempty"`
+}
+
+// CartStatus is where a cart is in its life. Only active carts can change
+// or be checked out.
+type CartStatus string
+
+const (
+	CartStatusActive    CartStatus = "active"
+	CartStatusConverted CartStatus = "converted" // checked out into an order
+	CartStatusAbandoned CartStatus = "abandoned" // left idle past the cart TTL
+)
+
+// IsActive reports whether the cart is still open. Carts saved before
+// statuses existed have none and count as active.
+func (c *Cart) IsActive() bool {
+	return c.Status == "" || c.Status == CartStatusActive
+}
+
+// Value is what the cart's items cost at the prices they were added at.
+func (c *Cart) Value() float64 {
+	var value float64
+	for _, item := range c.Items {
+		value += item.Price * float64(item.Quantity)
+	}
+	return value
+}
+
+// AbandonedCart is a cart left with items in it, for win-back campaigns.
+type AbandonedCart struct {
+	CartID       string     `json:"cart_id"`
+	UserID       int        `json:"user_id"`
+	Email        string     `json:"email,omitempty"`
+	Name         string     `json:"name,omitempty"`
+	Items        []CartItem `json:"items"`
+	Units        int        `json:"units"`
+	Value        float64    `json:"value"`
+	LastActivity time.Time  `json:"last_activity"`
+	AbandonedAt  time.Time  `json:"abandoned_at"`
 }
 
 type CartItem struct {
 	ProductID int     `json:"product_id"`
+	SKU       string  `json:"sku,omitempty"` // set for products with variants
 	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
 	Name      string  `json:"name"`
 	AddedAt   time.Time `json:"added_at"`
 }
 
+func (i CartItem) Ref() SKURef {
+	return SKURef{ProductID: i.ProductID, SKU: i.SKU}
+}
+
 type AddToCartRequest struct {
 	ProductID int `json:"product_id" binding:"required"`
+	SKU       string `json:"sku"` // required for products with variants
 	Quantity  int `json:"quantity" binding:"required,gt=0"`
 }
 
 type UpdateCartItemRequest struct {
-	Quantity int `json:"quantity" binding:"required,gt=0"`
+	Quantity *int `json:"quantity" binding:"required,gte=0"` // 0 removes the item
 }
\ No newline at end of file
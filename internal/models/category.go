@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// CategoryPathSeparator joins category names into a path such as
+// "Electronics > Audio > Headphones".
+const CategoryPathSeparator = " > "
+
+type Category struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`                // unique across the tree
+	ParentID  int       `json:"parent_id,omitempty"` // 0 for a top-level category
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CategoryNode is a category with its subtree, for browsing.
+type CategoryNode struct {
+	Category
+	Path     string          `json:"path"`
+	Children []*CategoryNode `json:"children"`
+}
+
+// Request models
+type CreateCategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=100,excludes=>"`
+	Slug     string `json:"slug" binding:"omitempty,max=100"` // generated from the name when empty
+	ParentID int    `json:"parent_id" binding:"gte=0"`
+}
+
+type UpdateCategoryRequest struct {
+	Name     string `json:"name" binding:"omitempty,max=100,excludes=>"`
+	Slug     string `json:"slug" binding:"omitempty,max=100"`
+	ParentID *int   `json:"parent_id" binding:"omitempty,gte=0"` // 0 moves it to the top level
+}
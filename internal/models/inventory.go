@@ -0,0 +1,171 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+type StockMovementType string
+
+const (
+	MovementInitial    StockMovementType = "initial"      // opening stock of a new product or SKU
+	MovementOrder      StockMovementType = "order"        // sold, or given back when checkout fails
+	MovementCancel     StockMovementType = "cancellation" // returned by a cancelled order
+	MovementAdjustment StockMovementType = "adjustment"   // set by hand or by a catalog change
+	MovementRestock    StockMovementType = "restock"      // received from a supplier
+)
+
+// ActorSystem marks changes made by the server itself (seeding, startup
+// migrations) rather than by a user.
+const ActorSystem = "system"
+
+// UserActor names a user as the actor of a stock change.
+func UserActor(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// StockMovement is one entry of the append-only inventory ledger. Quantity
+// is the SKU's stock right after the movement, so consecutive entries for
+// a SKU should chain: previous Quantity + Delta = Quantity.
+type StockMovement struct {
+	ID        int64             `json:"id"`
+	ProductID int               `json:"product_id"`
+	SKU       string            `json:"sku,omitempty"`
+	Type      StockMovementType `json:"type"`
+	Delta     int               `json:"delta"`
+	Quantity  int               `json:"quantity"`
+	Actor     string            `json:"actor"`
+	Reason    string            `json:"reason,omitempty"`
+	Reference string            `json:"reference,omitempty"` // e.g. the order ID
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func (m *StockMovement) Ref() SKURef {
+	return SKURef{ProductID: m.ProductID, SKU: m.SKU}
+}
+
+// StockChange describes why stock moves; it is copied onto every ledger
+// entry the change produces.
+type StockChange struct {
+	Type      StockMovementType
+	Actor     string
+	Reason    string
+	Reference string
+}
+
+// StockReconciliation compares one SKU's stock with what its ledger says.
+// Breaks counts entries whose Quantity does not follow from the entry
+// before, i.e. a stock write the ledger did not see in order (a lost
+// update).
+type StockReconciliation struct {
+	ProductID   int    `json:"product_id"`
+	SKU         string `json:"sku,omitempty"`
+	LedgerStock int    `json:"ledger_stock"` // sum of all deltas
+	ActualStock int    `json:"actual_stock"`
+	Drift       int    `json:"drift"` // actual - ledger
+	Movements   int    `json:"movements"`
+	Breaks      int    `json:"breaks"`
+}
+
+func (r StockReconciliation) OK() bool {
+	return r.Drift == 0 && r.Breaks == 0
+}
+
+// AdjustmentReason is the reason code of a manual stock adjustment.
+type AdjustmentReason string
+
+const (
+	ReasonReceived   AdjustmentReason = "received" // goods in from a supplier
+	ReasonReturned   AdjustmentReason = "returned" // customer return put back on the shelf
+	ReasonFound      AdjustmentReason = "found"
+	ReasonDamaged    AdjustmentReason = "damaged"
+	ReasonLost       AdjustmentReason = "lost"
+	ReasonCorrection AdjustmentReason = "correction" // stock count fix
+)
+
+func (r AdjustmentReason) Valid() bool {
+	switch r {
+	case ReasonReceived, ReasonReturned, ReasonFound, ReasonDamaged, ReasonLost, ReasonCorrection:
+		return true
+	}
+	return false
+}
+
+// MovementType is the ledger type of an adjustment with this reason:
+// receipts are restocks, everything else is an adjustment.
+func (r AdjustmentReason) MovementType() StockMovementType {
+	if r == ReasonReceived {
+		return MovementRestock
+	}
+	return MovementAdjustment
+}
+
+// StockAdjustment is one line of a relative stock change. With
+// ExpectedStock set the line only applies if the SKU's stock (at Location,
+// if given) is still that number (compare-and-set).
+type StockAdjustment struct {
+	ProductID     int    `json:"product_id"`
+	SKU           string `json:"sku"`      // required for products with variants
+	Location      string `json:"location"` // warehouse code; empty = stock not placed in a location
+	Delta         int    `json:"delta"`
+	ExpectedStock *int   `json:"expected_stock"`
+}
+
+func (a StockAdjustment) Ref() SKURef {
+	return SKURef{ProductID: a.ProductID, SKU: a.SKU}
+}
+
+// StockAdjustmentResult reports one applied adjustment line.
+type StockAdjustmentResult struct {
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku,omitempty"`
+	Location  string `json:"location,omitempty"`
+	OldStock  int    `json:"old_stock"` // at Location, if given
+	NewStock  int    `json:"new_stock"`
+}
+
+// StockLevel classifies a SKU's stock against its reorder point.
+type StockLevel string
+
+const (
+	StockOK  StockLevel = "ok"
+	StockLow StockLevel = "low" // at or below the reorder point
+	StockOut StockLevel = "out"
+)
+
+func LevelOf(stock, reorderPoint int) StockLevel {
+	switch {
+	case stock <= 0:
+		return StockOut
+	case stock <= reorderPoint:
+		return StockLow
+	}
+	return StockOK
+}
+
+// StockAlert is raised when a SKU drops to low or out of stock. It is
+// raised once per drop, not again while the level stays the same.
+type StockAlert struct {
+	ProductID    int        `json:"product_id"`
+	SKU          string     `json:"sku,omitempty"`
+	Name         string     `json:"name"`
+	Level        StockLevel `json:"level"`
+	Stock        int        `json:"stock"`
+	ReorderPoint int        `json:"reorder_point"`
+	At           time.Time  `json:"at"`
+}
+
+// LowStockItem is a SKU at or below its reorder point, with a suggestion
+// of how many units to reorder.
+type LowStockItem struct {
+	ProductID        int        `json:"product_id"`
+	SKU              string     `json:"sku,omitempty"`
+	Name             string     `json:"name"`
+	Level            StockLevel `json:"level"`
+	Stock            int        `json:"stock"`
+	Available        int        `json:"available"` // not held by carts
+	ReorderPoint     int        `json:"reorder_point"`
+	Sold             int        `json:"sold"` // units ordered during the velocity window
+	DailyVelocity    float64    `json:"daily_velocity"`
+	SuggestedReorder int        `json:"suggested_reorder"`
+}
@@ -12,25 +12,131 @@ const (
 	OrderStatusCancelled  OrderStatus = "cancelled"
 )
 
+// orderTransitions lists the statuses an order may move to from each status.
+// Delivered and cancelled are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered},
+	OrderStatusDelivered:  {},
+	OrderStatusCancelled:  {},
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Order struct {
 	ID         string      `json:"id"`
 	UserID     int         `json:"user_id"`
 	Items      []OrderItem `json:"items"`
 	Total      float64     `json:"total"`
 	Status     OrderStatus `json:"status"`
+	ShippingAddress ShippingAddress `json:"shipping_address"`
+	Payment    PaymentDetails `json:"payment"`
+	History    []OrderStatusChange `json:"history"`
 	CreatedAt  time.Time   `json:"created_at"`
 	UpdatedAt  time.Time   `json:"updated_at"`
 }
 
+type OrderStatusChange struct {
+	From   OrderStatus `json:"from,omitempty"`
+	To     OrderStatus `json:"to"`
+	Reason string      `json:"reason,omitempty"`
+	At     time.Time   `json:"at"`
+}
+
 type OrderItem struct {
 	ProductID int     `json:"product_id"`
+	SKU       string  `json:"sku,omitempty"`
 	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
 	Name      string  `json:"name"`
+	// Units still waiting for stock; restocks cover the oldest orders first
+	Backordered int        `json:"backordered,omitempty"`
+	ReleaseDate *time.Time `json:"release_date,omitempty"` // pre-ordered; ships from this date
+	// Where the units ship from. Units not listed are backordered or come
+	// from stock not placed in a location.
+	Allocations []LocationQuantity `json:"allocations,omitempty"`
+}
+
+func (i OrderItem) Ref() SKURef {
+	return SKURef{ProductID: i.ProductID, SKU: i.SKU}
+}
+
+// ReadyToShip reports whether every line is in stock and released.
+func (o *Order) ReadyToShip(now time.Time) bool {
+	for _, item := range o.Items {
+		if item.Backordered > 0 || (item.ReleaseDate != nil && now.Before(*item.ReleaseDate)) {
+			return false
+		}
+	}
+	return true
+}
+
+type ShippingAddress struct {
+	Name       string `json:"name" binding:"required,max=100"`
+	Line1      string `json:"line1" binding:"required,max=200"`
+	Line2      string `json:"line2,omitempty" binding:"max=200"`
+	City       string `json:"city" binding:"required,max=100"`
+	PostalCode string `json:"postal_code" binding:"required,max=20"`
+	Country    string `json:"country" binding:"required,iso3166_1_alpha2"` // e.g. "ID"
+}
+
+type PaymentMethod string
+
+const (
+	PaymentMethodCreditCard     PaymentMethod = "credit_card"
+	PaymentMethodBankTransfer   PaymentMethod = "bank_transfer"
+	PaymentMethodEWallet        PaymentMethod = "e_wallet"
+	PaymentMethodCashOnDelivery PaymentMethod = "cash_on_delivery"
+)
+
+// PaymentDetails never holds card numbers or other secrets, only what is
+// needed to show the customer how they paid.
+type PaymentDetails struct {
+	Method   PaymentMethod `json:"method" binding:"required,oneof=credit_card bank_transfer e_wallet cash_on_delivery"`
+	Provider string        `json:"provider,omitempty" binding:"max=50"` // e.g. "visa", "gopay"
 }
 
 type CreateOrderRequest struct {
-	CartID    string `json:"cart_id" binding:"required"`
-	Address   string `json:"address" binding:"required"`
-	PaymentMethod string `json:"payment_method" binding:"required"`
+	CartID  string          `json:"cart_id" binding:"required"`
+	Address ShippingAddress `json:"address" binding:"required"`
+	Payment PaymentDetails  `json:"payment" binding:"required"`
+}
+
+// OrderFilter narrows order listings. Zero values match everything.
+type OrderFilter struct {
+	Status OrderStatus
+	From   time.Time // created at or after
+	To     time.Time // created before
+}
+
+func (f OrderFilter) Matches(order *Order) bool {
+	if f.Status != "" && order.Status != f.Status {
+		return false
+	}
+	if !f.From.IsZero() && order.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !order.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// IsValid reports whether s is one of the known statuses.
+func (s OrderStatus) IsValid() bool {
+	_, known := orderTransitions[s]
+	return known
+}
+
+type OrderTransitionRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
 }
\ No newline at end of file
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PageRequest selects one page of a list, by page number or, when After
+// is set, by cursor.
+type PageRequest struct {
+	Page  int
+	Limit int
+	After *Cursor
+}
+
+// Cursor is the position of the last item of a page: its sort key and ID.
+// The next page starts after it, however the list changed in between.
+// Clients only ever see it as an opaque token.
+type Cursor struct {
+	Sort string    `json:"s,omitempty"` // the sort the cursor was taken from
+	Num  float64   `json:"n,omitempty"` // numeric sort key (score, price, stock)
+	Text string    `json:"t,omitempty"` // text sort key (name)
+	Time time.Time `json:"at,omitzero"` // time sort key (created_at)
+	ID   string    `json:"id"`
+}
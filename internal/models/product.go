@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 type Product struct {
 	ID          int       `json:"id"`
@@ -8,18 +12,242 @@ type Product struct {
 	Description string    `json:"description"`
 	Price       float64   `json:"price"`
 	Stock       int       `json:"stock"`
-	Category    string    `json:"category"`
+	Category    string    `json:"category"` // path of CategoryID, kept in sync by the service
+	CategoryID  int       `json:"category_id"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // soft delete, kept so old orders still resolve
+	// With variants, Price is the lowest variant price and Stock the sum of
+	// variant stock; both are kept in sync by SyncVariants.
+	Variants []Variant `json:"variants,omitempty"`
+	// In display order; the first image is the main one
+	Images []ProductImage `json:"images,omitempty"`
+	// Stock not held by carts; only filled in on product responses
+	Available *int `json:"available,omitempty"`
+	// Stock level at or below which each SKU counts as low; 0 uses the
+	// store-wide default
+	ReorderPoint int `json:"reorder_point,omitempty"`
+	// What checkout does when a SKU runs short; empty means PolicyDeny.
+	// Units owed to backorders are deducted too, so stock goes negative.
+	StockPolicy    StockPolicy `json:"stock_policy,omitempty"`
+	BackorderLimit int         `json:"backorder_limit,omitempty"` // most units a SKU may owe; 0 = no limit
+	ReleaseDate    *time.Time  `json:"release_date,omitempty"`    // pre-orders ship from this date
+	// Stock per warehouse code, for products without variants that track
+	// it; see LocatedStock
+	Locations map[string]int `json:"locations,omitempty"`
+}
+
+// StockPolicy decides whether checkout may sell more than is available.
+type StockPolicy string
+
+const (
+	PolicyDeny      StockPolicy = "deny"      // the order fails
+	PolicyBackorder StockPolicy = "backorder" // the short lines wait for a restock
+	PolicyPreorder  StockPolicy = "preorder"  // like backorder, for a product not released yet
+)
+
+// IsValid reports whether p is a known policy; empty counts as PolicyDeny.
+func (p StockPolicy) IsValid() bool {
+	switch p {
+	case "", PolicyDeny, PolicyBackorder, PolicyPreorder:
+		return true
+	}
+	return false
+}
+
+// AllowsBackorders reports whether p lets checkout sell beyond stock.
+func (p StockPolicy) AllowsBackorders() bool {
+	return p == PolicyBackorder || p == PolicyPreorder
+}
+
+// Backorderable reports whether checkout may take quantity units of a SKU
+// that has only available units free: either enough are free, or the
+// product takes backorders and the SKU would owe no more than
+// BackorderLimit.
+func (p *Product) Backorderable(available, quantity int) bool {
+	if quantity <= available {
+		return true
+	}
+	if !p.StockPolicy.AllowsBackorders() {
+		return false
+	}
+	return p.BackorderLimit == 0 || quantity-available <= p.BackorderLimit
+}
+
+// Variant is a sellable version of a product (a SKU), e.g. one size and
+// colour of a T-shirt. SKU codes are unique within their product.
+type Variant struct {
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options,omitempty"` // e.g. size: M, colour: red
+	Price   float64           `json:"price"`
+	Stock   int               `json:"stock"`
+	// Stock not held by carts; only filled in on product responses
+	Available *int `json:"available,omitempty"`
+	// Stock per warehouse code, when tracked
+	Locations map[string]int `json:"locations,omitempty"`
+}
+
+// Label is the option values in option-name order, e.g. "red / M".
+func (v *Variant) Label() string {
+	names := make([]string, 0, len(v.Options))
+	for name := range v.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, v.Options[name])
+	}
+	return strings.Join(values, " / ")
+}
+
+// ProductImage is an uploaded image and its thumbnail. Keys locate the
+// files in media storage; URLs are where clients fetch them.
+type ProductImage struct {
+	ID           string    `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Key          string    `json:"key"`
+	ThumbnailKey string    `json:"thumbnail_key"`
+	Alt          string    `json:"alt"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position"` // index in Product.Images
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SKURef identifies what is being bought: a product without variants
+// (empty SKU) or one variant of a product.
+type SKURef struct {
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku,omitempty"`
+}
+
+func (p *Product) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
+
+func (p *Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+func (p *Product) Variant(sku string) (*Variant, bool) {
+	for i := range p.Variants {
+		if p.Variants[i].SKU == sku {
+			return &p.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// Unit returns the name, price and stock of what sku sells: the variant,
+// or the product itself when sku is empty. ok is false when sku does not
+// fit the product: unknown, or empty for a product with variants.
+func (p *Product) Unit(sku string) (name string, price float64, stock int, ok bool) {
+	if sku == "" {
+		return p.Name, p.Price, p.Stock, !p.HasVariants()
+	}
+	variant, exists := p.Variant(sku)
+	if !exists {
+		return "", 0, 0, false
+	}
+	if label := variant.Label(); label != "" {
+		name = p.Name + " (" + label + ")"
+	} else {
+		name = p.Name
+	}
+	return name, variant.Price, variant.Stock, true
+}
+
+// AddStock adds delta to the stock of sku (see Unit) and reports whether
+// sku fits the product. It does not check for negative stock.
+func (p *Product) AddStock(sku string, delta int) bool {
+	if sku == "" {
+		if p.HasVariants() {
+			return false
+		}
+		p.Stock += delta
+		return true
+	}
+	variant, exists := p.Variant(sku)
+	if !exists {
+		return false
+	}
+	variant.Stock += delta
+	p.SyncVariants()
+	return true
+}
+
+// SyncVariants recomputes the product's Price and Stock from its
+// variants. It does nothing for a product without variants.
+func (p *Product) SyncVariants() {
+	if !p.HasVariants() {
+		return
+	}
+	p.Price = p.Variants[0].Price
+	p.Stock = 0
+	for _, variant := range p.Variants {
+		if variant.Price < p.Price {
+			p.Price = variant.Price
+		}
+		p.Stock += variant.Stock
+	}
 }
 
 // Request models
 type CreateProductRequest struct {
 	Name        string  `json:"name" binding:"required,min=3"`
 	Description string  `json:"description" binding:"required,min=10"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Stock       int     `json:"stock" binding:"required,gte=0"`
-	Category    string  `json:"category" binding:"required"`
+	Price       float64 `json:"price" binding:"required_without=Variants,omitempty,gt=0"`
+	Stock       int     `json:"stock" binding:"gte=0"` // 0 is a valid stock level, so not "required"
+	// A category path like "Electronics > Audio"; missing levels are
+	// created. Ignored when CategoryID is set.
+	Category   string `json:"category" binding:"required_without=CategoryID"`
+	CategoryID int    `json:"category_id" binding:"gte=0"`
+	// Optional; with variants, Price and Stock above are derived from them
+	Variants []VariantRequest `json:"variants" binding:"omitempty,dive"`
+	// 0 uses the store-wide default; a pointer so an import row that leaves
+	// it out keeps the product's current value
+	ReorderPoint *int `json:"reorder_point" binding:"omitempty,gte=0"`
+	// Defaults to deny; preorder needs a release date
+	StockPolicy    StockPolicy `json:"stock_policy" binding:"omitempty,oneof=deny backorder preorder"`
+	BackorderLimit int         `json:"backorder_limit" binding:"gte=0"`
+	ReleaseDate    *time.Time  `json:"release_date"`
+}
+
+type VariantRequest struct {
+	SKU     string            `json:"sku" binding:"required,max=64"`
+	Options map[string]string `json:"options"`
+	Price   float64           `json:"price" binding:"required,gt=0"`
+	Stock   int               `json:"stock" binding:"gte=0"`
+}
+
+// UpsertVariantRequest is the body of PUT .../variants/:sku
+type UpsertVariantRequest struct {
+	Options map[string]string `json:"options"`
+	Price   float64           `json:"price" binding:"required,gt=0"`
+	Stock   int               `json:"stock" binding:"gte=0"`
+}
+
+// UpdateImageRequest is the body of PATCH .../images/:image_id. Position
+// moves the image; the others shift to make room.
+type UpdateImageRequest struct {
+	Alt      *string `json:"alt" binding:"omitempty,max=250"`
+	Position *int    `json:"position" binding:"omitempty,gte=0"`
+}
+
+// ReorderImagesRequest lists every image ID of a product in the new order.
+type ReorderImagesRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1"`
+}
+
+// ProductImport is one catalog import row. ID 0 creates a new product;
+// otherwise the product with that ID is created or replaced.
+type ProductImport struct {
+	ID int `json:"id"`
+	CreateProductRequest
 }
 
 type UpdateProductRequest struct {
@@ -27,5 +255,73 @@ type UpdateProductRequest struct {
 	Description string  `json:"description" binding:"omitempty,min=10"`
 	Price       float64 `json:"price" binding:"omitempty,gt=0"`
 	Stock       int     `json:"stock" binding:"omitempty,gte=0"`
-	Category    string  `json:"category"`
-}
\ No newline at end of file
+	Category    string  `json:"category"` // path, as in CreateProductRequest
+	CategoryID  int     `json:"category_id" binding:"gte=0"`
+	// A pointer so 0 (back to the store-wide default) can be set
+	ReorderPoint   *int        `json:"reorder_point" binding:"omitempty,gte=0"`
+	StockPolicy    StockPolicy `json:"stock_policy" binding:"omitempty,oneof=deny backorder preorder"`
+	BackorderLimit *int        `json:"backorder_limit" binding:"omitempty,gte=0"`
+	ReleaseDate    *time.Time  `json:"release_date"`
+}
+// ProductSort orders product listings and search results. Every order
+// falls back to product ID so pages never overlap.
+type ProductSort string
+
+const (
+	SortRelevance ProductSort = "relevance" // search default; ID order without a query
+	SortPriceAsc  ProductSort = "price_asc"
+	SortPriceDesc ProductSort = "price_desc"
+	SortNewest    ProductSort = "newest"
+	SortName      ProductSort = "name"
+	SortStock     ProductSort = "stock" // most stock first
+)
+
+// IsValid reports whether s is a known sort. The empty sort is valid and
+// means the default order.
+func (s ProductSort) IsValid() bool {
+	switch s {
+	case "", SortRelevance, SortPriceAsc, SortPriceDesc, SortNewest, SortName, SortStock:
+		return true
+	}
+	return false
+}
+
+// ProductSearch is a catalog search. Zero values match everything.
+type ProductSearch struct {
+	Query      string
+	Categories []int // category IDs; matches any of them or their descendants
+	MinPrice   float64
+	MaxPrice   float64
+	Sort       ProductSort
+	Page       PageRequest
+}
+
+// SearchFacets summarise every product matching a search, not just the
+// returned page.
+type SearchFacets struct {
+	Categories   []CategoryFacet `json:"categories"`
+	PriceRanges  []PriceBucket   `json:"price_ranges"`
+	Availability StockFacet      `json:"availability"`
+}
+
+// CategoryFacet counts ignore the category filter itself, so the
+// storefront can show how many results selecting another category adds.
+type CategoryFacet struct {
+	ID       int    `json:"id"`
+	Slug     string `json:"slug"`
+	Category string `json:"category"` // path
+	Count    int    `json:"count"` // includes subcategories
+	Selected bool   `json:"selected"`
+}
+
+// PriceBucket covers Min <= price < Max. Max 0 means no upper bound.
+type PriceBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max,omitempty"`
+	Count int     `json:"count"`
+}
+
+type StockFacet struct {
+	InStock    int `json:"in_stock"`
+	OutOfStock int `json:"out_of_stock"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleStaff    Role = "staff"
+	RoleAdmin    Role = "admin"
+)
+
+func (r Role) IsValid() bool {
+	return r == RoleCustomer || r == RoleStaff || r == RoleAdmin
+}
+
+type User struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	Name         string    `json:"name"`
+	Role         Role      `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Request models
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,min=8,max=72"` // bcrypt limit
+	Name     string `json:"name" binding:"required,min=2,max=100"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type UpdateProfileRequest struct {
+	Name string `json:"name" binding:"omitempty,min=2,max=100"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=customer staff admin"`
+}
@@ -0,0 +1,85 @@
+package models
+
+// Warehouse is a location stock is kept and shipped from.
+type Warehouse struct {
+	Code        string  `json:"code"` // referenced by per-location stock
+	Name        string  `json:"name"`
+	Country     string  `json:"country"` // ISO 3166-1 alpha-2, like ShippingAddress
+	City        string  `json:"city"`
+	PostalCode  string  `json:"postal_code"`
+	CostPerUnit float64 `json:"cost_per_unit"` // handling and shipping cost of one unit
+	Priority    int     `json:"priority"`      // lower goes first when nothing else decides
+}
+
+// AllocationStrategy decides which locations an order's lines are taken
+// from.
+type AllocationStrategy string
+
+const (
+	AllocateNearest        AllocationStrategy = "nearest"         // closest to the shipping address
+	AllocateSingleLocation AllocationStrategy = "single_location" // one location that has every line, if any
+	AllocateLowestCost     AllocationStrategy = "lowest_cost"     // cheapest CostPerUnit
+)
+
+func (s AllocationStrategy) IsValid() bool {
+	switch s {
+	case AllocateNearest, AllocateSingleLocation, AllocateLowestCost:
+		return true
+	}
+	return false
+}
+
+// LocationQuantity is how many units of an order line one location
+// fulfils.
+type LocationQuantity struct {
+	Location string `json:"location"`
+	Quantity int    `json:"quantity"`
+}
+
+// UnitLocations returns the per-location stock of sku (see Unit), or nil
+// when the SKU is not tracked per location. The map must not be changed;
+// use AddLocationStock.
+func (p *Product) UnitLocations(sku string) map[string]int {
+	if sku == "" {
+		return p.Locations
+	}
+	if variant, exists := p.Variant(sku); exists {
+		return variant.Locations
+	}
+	return nil
+}
+
+// AddLocationStock adds delta to the stock of sku at location and reports
+// whether sku fits the product. It leaves the SKU's total alone: callers
+// keep it in step with AddStock. The location map is replaced rather than
+// changed, so copies of the product stay valid.
+func (p *Product) AddLocationStock(sku, location string, delta int) bool {
+	if _, _, _, ok := p.Unit(sku); !ok {
+		return false
+	}
+
+	locations := make(map[string]int, len(p.UnitLocations(sku))+1)
+	for code, stock := range p.UnitLocations(sku) {
+		locations[code] = stock
+	}
+	locations[location] += delta
+
+	if sku == "" {
+		p.Locations = locations
+	} else {
+		variant, _ := p.Variant(sku)
+		variant.Locations = locations
+	}
+	return true
+}
+
+// LocatedStock is the stock of sku placed in some location. A SKU's stock
+// minus its located stock is stock not placed anywhere yet, or, when
+// negative, units owed to backorders.
+func LocatedStock(locations map[string]int) int {
+	total := 0
+	for _, stock := range locations {
+		total += stock
+	}
+	return total
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"go-ecommerce/internal/models"
+)
+
+type CartRepository interface {
+	FindByID(id string) (*models.Cart, bool)
+	FindByUserID(userID int) (*models.Cart, bool) // most recently created cart
+	FindAll() []*models.Cart
+	Save(cart *models.Cart) error
+	Delete(id string) error
+}
+
+// ============================================
+// IN-MEMORY
+// ============================================
+type MemoryCartRepository struct {
+	mu        sync.RWMutex
+	carts     map[string]*models.Cart // cart_id -> cart
+	userCarts map[int]string          // user_id -> cart_id
+}
+
+func NewMemoryCartRepository() *MemoryCartRepository {
+	return &MemoryCartRepository{
+		carts:     make(map[string]*models.Cart),
+		userCarts: make(map[int]string),
+	}
+}
+
+func (r *MemoryCartRepository) FindByID(id string) (*models.Cart, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cart, exists := r.carts[id]
+	return cart, exists
+}
+
+func (r *MemoryCartRepository) FindByUserID(userID int) (*models.Cart, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cartID, exists := r.userCarts[userID]
+	if !exists {
+		return nil, false
+	}
+
+	cart, exists := r.carts[cartID]
+	return cart, exists
+}
+
+func (r *MemoryCartRepository) FindAll() []*models.Cart {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	carts := make([]*models.Cart, 0, len(r.carts))
+	for _, cart := range r.carts {
+		carts = append(carts, cart)
+	}
+	sort.Slice(carts, func(i, j int) bool {
+		return carts[i].CreatedAt.Before(carts[j].CreatedAt)
+	})
+	return carts
+}
+
+// Save stores the cart. A cart seen for the first time becomes its user's
+// current cart.
+func (r *MemoryCartRepository) Save(cart *models.Cart) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.carts[cart.ID]; !exists {
+		r.userCarts[cart.UserID] = cart.ID
+	}
+	r.carts[cart.ID] = cart
+	return nil
+}
+
+func (r *MemoryCartRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cart, exists := r.carts[id]
+	if !exists {
+		return nil
+	}
+	if r.userCarts[cart.UserID] == id {
+		delete(r.userCarts, cart.UserID)
+	}
+	delete(r.carts, id)
+	return nil
+}
+
+// ============================================
+// FILE-BACKED
+// ============================================
+type FileCartRepository struct {
+	*MemoryCartRepository
+	journal *journal
+}
+
+func NewFileCartRepository(path string) (*FileCartRepository, error) {
+	memory := NewMemoryCartRepository()
+
+	j, err := openJournal(path, func(entry journalEntry) error {
+		switch entry.Op {
+		case journalOpPut:
+			var cart models.Cart
+			if err := json.Unmarshal(entry.Data, &cart); err != nil {
+				return err
+			}
+			return memory.Save(&cart)
+		case journalOpDelete:
+			return memory.Delete(entry.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileCartRepository{MemoryCartRepository: memory, journal: j}
+	if err := r.compact(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileCartRepository) Save(cart *models.Cart) error {
+	if err := r.MemoryCartRepository.Save(cart); err != nil {
+		return err
+	}
+	return r.journal.put(cart.ID, cart)
+}
+
+func (r *FileCartRepository) Delete(id string) error {
+	if err := r.MemoryCartRepository.Delete(id); err != nil {
+		return err
+	}
+	return r.journal.delete(id)
+}
+
+// compact writes carts oldest first so replay restores each user's
+// current cart.
+func (r *FileCartRepository) compact() error {
+	return r.journal.compact(func(write func(string, interface{}) error) error {
+		for _, cart := range r.FindAll() {
+			if err := write(cart.ID, cart); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FileCartRepository) Close() error {
+	return r.journal.Close()
+}
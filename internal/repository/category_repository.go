@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go-ecommerce/internal/models"
+)
+
+type CategoryRepository interface {
+	FindByID(id int) (*models.Category, bool)
+	FindAll() []*models.Category // ordered by ID
+	Count() int
+	MaxID() int
+	Save(category *models.Category) error
+	Delete(id int) error
+}
+
+// ============================================
+// IN-MEMORY
+// ============================================
+type MemoryCategoryRepository struct {
+	mu         sync.RWMutex
+	categories map[int]*models.Category
+}
+
+func NewMemoryCategoryRepository() *MemoryCategoryRepository {
+	return &MemoryCategoryRepository{
+		categories: make(map[int]*models.Category),
+	}
+}
+
+func (r *MemoryCategoryRepository) FindByID(id int) (*models.Category, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, exists := r.categories[id]
+	return category, exists
+}
+
+func (r *MemoryCategoryRepository) FindAll() []*models.Category {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := make([]*models.Category, 0, len(r.categories))
+	for _, category := range r.categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].ID < categories[j].ID
+	})
+	return categories
+}
+
+func (r *MemoryCategoryRepository) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.categories)
+}
+
+func (r *MemoryCategoryRepository) MaxID() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	maxID := 0
+	for id := range r.categories {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	return maxID
+}
+
+func (r *MemoryCategoryRepository) Save(category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.categories[category.ID] = category
+	return nil
+}
+
+func (r *MemoryCategoryRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.categories, id)
+	return nil
+}
+
+// ============================================
+// FILE-BACKED
+// ============================================
+type FileCategoryRepository struct {
+	*MemoryCategoryRepository
+	journal *journal
+}
+
+func NewFileCategoryRepository(path string) (*FileCategoryRepository, error) {
+	memory := NewMemoryCategoryRepository()
+
+	j, err := openJournal(path, func(entry journalEntry) error {
+		id, err := strconv.Atoi(entry.Key)
+		if err != nil {
+			return fmt.Errorf("invalid category key %q", entry.Key)
+		}
+		switch entry.Op {
+		case journalOpPut:
+			var category models.Category
+			if err := json.Unmarshal(entry.Data, &category); err != nil {
+				return err
+			}
+			memory.categories[id] = &category
+		case journalOpDelete:
+			delete(memory.categories, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileCategoryRepository{MemoryCategoryRepository: memory, journal: j}
+	if err := r.compact(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileCategoryRepository) Save(category *models.Category) error {
+	if err := r.MemoryCategoryRepository.Save(category); err != nil {
+		return err
+	}
+	return r.journal.put(strconv.Itoa(category.ID), category)
+}
+
+func (r *FileCategoryRepository) Delete(id int) error {
+	if err := r.MemoryCategoryRepository.Delete(id); err != nil {
+		return err
+	}
+	return r.journal.delete(strconv.Itoa(id))
+}
+
+func (r *FileCategoryRepository) compact() error {
+	return r.journal.compact(func(write func(string, interface{}) error) error {
+		for _, category := range r.FindAll() {
+			if err := write(strconv.Itoa(category.ID), category); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FileCategoryRepository) Close() error {
+	return r.journal.Close()
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// journal is an append-only JSON Lines file used by the file-backed
+// repositories. Every write appends one entry; on open the entries are
+// replayed and the file is compacted so it only holds the latest state.
+type journal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+type journalEntry struct {
+	Op   string          `json:"op"` // "put" or "delete"
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+const (
+	journalOpPut    = "put"
+	journalOpDelete = "delete"
+)
+
+// openJournal replays the file at path through apply and opens it for
+// appending. A missing file is treated as an empty journal.
+func openJournal(path string, apply func(entry journalEntry) error) (*journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var entry journalEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			if err := apply(entry); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{path: path, file: file}, nil
+}
+
+func (j *journal) put(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return j.append(journalEntry{Op: journalOpPut, Key: key, Data: data})
+}
+
+func (j *journal) delete(key string) error {
+	return j.append(journalEntry{Op: journalOpDelete, Key: key})
+}
+
+func (j *journal) append(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return fmt.Errorf("journal %s is closed", j.path)
+	}
+	_, err = j.file.Write(line)
+	return err
+}
+
+// compact rewrites the journal so it holds one put per live record.
+// snapshot must call write once for every record to keep.
+func (j *journal) compact(snapshot func(write func(key string, value interface{}) error) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+
+	err = snapshot(func(key string, value interface{}) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(journalEntry{Op: journalOpPut, Key: key, Data: data})
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return err
+	}
+	j.file, err = os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	return err
+}
+
+func (j *journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Sync()
+	if cerr := j.file.Close(); err == nil {
+		err = cerr
+	}
+	j.file = nil
+	return err
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"go-ecommerce/internal/models"
+)
+
+// LedgerRepository is the append-only inventory ledger. Entries are never
+// changed or removed.
+type LedgerRepository interface {
+	Append(movement *models.StockMovement) error
+	FindByProduct(productID int) []*models.StockMovement // oldest first
+	FindAll() []*models.StockMovement                    // oldest first
+	MaxID() int64
+}
+
+// ============================================
+// IN-MEMORY
+// ============================================
+type MemoryLedgerRepository struct {
+	mu        sync.RWMutex
+	movements []*models.StockMovement
+	byProduct map[int][]int // product_id -> indexes into movements
+	maxID     int64
+}
+
+func NewMemoryLedgerRepository() *MemoryLedgerRepository {
+	return &MemoryLedgerRepository{
+		byProduct: make(map[int][]int),
+	}
+}
+
+func (r *MemoryLedgerRepository) Append(movement *models.StockMovement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byProduct[movement.ProductID] = append(r.byProduct[movement.ProductID], len(r.movements))
+	r.movements = append(r.movements, movement)
+	if movement.ID > r.maxID {
+		r.maxID = movement.ID
+	}
+	return nil
+}
+
+func (r *MemoryLedgerRepository) FindByProduct(productID int) []*models.StockMovement {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	indexes := r.byProduct[productID]
+	movements := make([]*models.StockMovement, 0, len(indexes))
+	for _, i := range indexes {
+		movements = append(movements, r.movements[i])
+	}
+	return movements
+}
+
+func (r *MemoryLedgerRepository) FindAll() []*models.StockMovement {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*models.StockMovement(nil), r.movements...)
+}
+
+func (r *MemoryLedgerRepository) MaxID() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.maxID
+}
+
+// ============================================
+// FILE-BACKED
+// ============================================
+type FileLedgerRepository struct {
+	*MemoryLedgerRepository
+	journal *journal
+}
+
+// NewFileLedgerRepository replays the ledger. Unlike the other journals it
+// is not compacted: every entry is already a live record.
+func NewFileLedgerRepository(path string) (*FileLedgerRepository, error) {
+	memory := NewMemoryLedgerRepository()
+
+	j, err := openJournal(path, func(entry journalEntry) error {
+		if entry.Op != journalOpPut {
+			return nil
+		}
+		var movement models.StockMovement
+		if err := json.Unmarshal(entry.Data, &movement); err != nil {
+			return err
+		}
+		return memory.Append(&movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileLedgerRepository{MemoryLedgerRepository: memory, journal: j}, nil
+}
+
+func (r *FileLedgerRepository) Append(movement *models.StockMovement) error {
+	if err := r.MemoryLedgerRepository.Append(movement); err != nil {
+		return err
+	}
+	return r.journal.put(strconv.FormatInt(movement.ID, 10), movement)
+}
+
+func (r *FileLedgerRepository) Close() error {
+	return r.journal.Close()
+}
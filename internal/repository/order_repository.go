@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"encoding/json"
+	"sync"
+
+	"go-ecommerce/internal/models"
+)
+
+type OrderRepository interface {
+	FindByID(id string) (*models.Order, bool)
+	FindByUserID(userID int) []*models.Order // oldest first
+	FindAll() []*models.Order                // oldest first
+	Save(order *models.Order) error
+}
+
+// ============================================
+// IN-MEMORY
+// ============================================
+type MemoryOrderRepository struct {
+	mu         sync.RWMutex
+	orders     map[string]*models.Order // order_id -> order
+	orderIDs   []string                 // insertion order
+	userOrders map[int][]string         // user_id -> order_ids
+}
+
+func NewMemoryOrderRepository() *MemoryOrderRepository {
+	return &MemoryOrderRepository{
+		orders:     make(map[string]*models.Order),
+		userOrders: make(map[int][]string),
+	}
+}
+
+func (r *MemoryOrderRepository) FindByID(id string) (*models.Order, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, exists := r.orders[id]
+	return order, exists
+}
+
+func (r *MemoryOrderRepository) FindByUserID(userID int) []*models.Order {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orderIDs := r.userOrders[userID]
+	orders := make([]*models.Order, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		orders = append(orders, r.orders[id])
+	}
+	return orders
+}
+
+func (r *MemoryOrderRepository) FindAll() []*models.Order {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := make([]*models.Order, 0, len(r.orderIDs))
+	for _, id := range r.orderIDs {
+		orders = append(orders, r.orders[id])
+	}
+	return orders
+}
+
+func (r *MemoryOrderRepository) Save(order *models.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.orders[order.ID]; !exists {
+		r.orderIDs = append(r.orderIDs, order.ID)
+		r.userOrders[order.UserID] = append(r.userOrders[order.UserID], order.ID)
+	}
+	r.orders[order.ID] = order
+	return nil
+}
+
+// ============================================
+// FILE-BACKED
+// ============================================
+type FileOrderRepository struct {
+	*MemoryOrderRepository
+	journal *journal
+}
+
+func NewFileOrderRepository(path string) (*FileOrderRepository, error) {
+	memory := NewMemoryOrderRepository()
+
+	j, err := openJournal(path, func(entry journalEntry) error {
+		if entry.Op != journalOpPut {
+			return nil
+		}
+		var order models.Order
+		if err := json.Unmarshal(entry.Data, &order); err != nil {
+			return err
+		}
+		return memory.Save(&order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileOrderRepository{MemoryOrderRepository: memory, journal: j}
+	if err := r.compact(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileOrderRepository) Save(order *models.Order) error {
+	if err := r.MemoryOrderRepository.Save(order); err != nil {
+		return err
+	}
+	return r.journal.put(order.ID, order)
+}
+
+func (r *FileOrderRepository) compact() error {
+	return r.journal.compact(func(write func(string, interface{}) error) error {
+		for _, order := range r.FindAll() {
+			if err := write(order.ID, order); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FileOrderRepository) Close() error {
+	return r.journal.Close()
+}
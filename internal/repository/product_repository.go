@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go-ecommerce/internal/models"
+)
+
+type ProductRepository interface {
+	FindByID(id int) (*models.Product, bool)
+	FindAll() []*models.Product // ordered by ID
+	Count() int
+	MaxID() int
+	Save(product *models.Product) error
+	Delete(id int) error
+}
+
+// ============================================
+// IN-MEMORY
+// ============================================
+type MemoryProductRepository struct {
+	mu       sync.RWMutex
+	products map[int]*models.Product
+}
+
+func NewMemoryProductRepository() *MemoryProductRepository {
+	return &MemoryProductRepository{
+		products: make(map[int]*models.Product),
+	}
+}
+
+func (r *MemoryProductRepository) FindByID(id int) (*models.Product, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, exists := r.products[id]
+	return product, exists
+}
+
+func (r *MemoryProductRepository) FindAll() []*models.Product {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]*models.Product, 0, len(r.products))
+	for _, product := range r.products {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+	return products
+}
+
+func (r *MemoryProductRepository) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.products)
+}
+
+func (r *MemoryProductRepository) MaxID() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	maxID := 0
+	for id := range r.products {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	return maxID
+}
+
+func (r *MemoryProductRepository) Save(product *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products[product.ID] = product
+	return nil
+}
+
+func (r *MemoryProductRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.products, id)
+	return nil
+}
+
+// ============================================
+// FILE-BACKED
+// ============================================
+type FileProductRepository struct {
+	*MemoryProductRepository
+	journal *journal
+}
+
+func NewFileProductRepository(path string) (*FileProductRepository, error) {
+	memory := NewMemoryProductRepository()
+
+	j, err := openJournal(path, func(entry journalEntry) error {
+		id, err := strconv.Atoi(entry.Key)
+		if err != nil {
+			return fmt.Errorf("invalid product key %q", entry.Key)
+		}
+		switch entry.Op {
+		case journalOpPut:
+			var product models.Product
+			if err := json.Unmarshal(entry.Data, &product); err != nil {
+				return err
+			}
+			memory.products[id] = &product
+		case journalOpDelete:
+			delete(memory.products, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileProductRepository{MemoryProductRepository: memory, journal: j}
+	if err := r.compact(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileProductRepository) Save(product *models.Product) error {
+	if err := r.MemoryProductRepository.Save(product); err != nil {
+		return err
+	}
+	return r.journal.put(strconv.Itoa(product.ID), product)
+}
+
+func (r *FileProductRepository) Delete(id int) error {
+	if err := r.MemoryProductRepository.Delete(id); err != nil {
+		return err
+	}
+	return r.journal.delete(strconv.Itoa(id))
+}
+
+func (r *FileProductRepository) compact() error {
+	return r.journal.compact(func(write func(string, interface{}) error) error {
+		for _, product := range r.FindAll() {
+			if err := write(strconv.Itoa(product.ID), product); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FileProductRepository) Close() error {
+	return r.journal.Close()
+}
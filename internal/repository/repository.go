@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Store bundles the repositories the services depend on so main can pick
+// a storage driver in one place.
+type Store struct {
+	Products   ProductRepository
+	Carts      CartRepository
+	Orders     OrderRepository
+	Users      UserRepository
+	Categories CategoryRepository
+	Ledger     LedgerRepository
+
+	closers []func() error
+}
+
+// NewMemoryStore keeps everything in process memory. Data is lost on restart.
+func NewMemoryStore() *Store {
+	return &Store{
+		Products:   NewMemoryProductRepository(),
+		Carts:      NewMemoryCartRepository(),
+		Orders:     NewMemoryOrderRepository(),
+		Users:      NewMemoryUserRepository(),
+		Categories: NewMemoryCategoryRepository(),
+		Ledger:     NewMemoryLedgerRepository(),
+	}
+}
+
+// NewFileStore persists every repository as a JSON Lines journal under dir.
+func NewFileStore(dir string) (*Store, error) {
+	store := &Store{}
+
+	products, err := NewFileProductRepository(filepath.Join(dir, "products.jsonl"))
+	if err != nil {
+		return nil, store.fail("product", err)
+	}
+	store.Products = products
+	store.closers = append(store.closers, products.Close)
+
+	carts, err := NewFileCartRepository(filepath.Join(dir, "carts.jsonl"))
+	if err != nil {
+		return nil, store.fail("cart", err)
+	}
+	store.Carts = carts
+	store.closers = append(store.closers, carts.Close)
+
+	orders, err := NewFileOrderRepository(filepath.Join(dir, "orders.jsonl"))
+	if err != nil {
+		return nil, store.fail("order", err)
+	}
+	store.Orders = orders
+	store.closers = append(store.closers, orders.Close)
+
+	users, err := NewFileUserRepository(filepath.Join(dir, "users.jsonl"))
+	if err != nil {
+		return nil, store.fail("user", err)
+	}
+	store.Users = users
+	store.closers = append(store.closers, users.Close)
+
+	categories, err := NewFileCategoryRepository(filepath.Join(dir, "categories.jsonl"))
+	if err != nil {
+		return nil, store.fail("category", err)
+	}
+	store.Categories = categories
+	store.closers = append(store.closers, categories.Close)
+
+	ledger, err := NewFileLedgerRepository(filepath.Join(dir, "ledger.jsonl"))
+	if err != nil {
+		return nil, store.fail("ledger", err)
+	}
+	store.Ledger = ledger
+	store.closers = append(store.closers, ledger.Close)
+
+	return store, nil
+}
+
+// fail closes whatever was opened so far and wraps err.
+func (s *Store) fail(name string, err error) error {
+	s.Close()
+	return fmt.Errorf("open %s store: %w", name, err)
+}
+
+// Open returns the store for the given driver name ("memory" or "file").
+func Open(driver, dataDir string) (*Store, error) {
+	switch driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(dataDir)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// Close flushes and closes any open files.
+func (s *Store) Close() error {
+	var firstErr error
+	for _, closeFn := range s.closers {
+		if err := closeFn(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go-ecommerce/internal/models"
+)
+
+// userRecord is the stored form of a user. models.User hides the password
+// hash from JSON, so the file store needs its own shape.
+type userRecord struct {
+	models.User
+	PasswordHash string `json:"password_hash"`
+}
+
+type UserRepository interface {
+	FindByID(id int) (*models.User, bool)
+	FindByEmail(email string) (*models.User, bool)
+	Count() int
+	MaxID() int
+	Save(user *models.User) error
+}
+
+// ============================================
+// IN-MEMORY
+// ============================================
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[int]*models.User
+	emails map[string]int // email -> user_id
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:  make(map[int]*models.User),
+		emails: make(map[string]int),
+	}
+}
+
+func (r *MemoryUserRepository) FindByID(id int) (*models.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	return user, exists
+}
+
+func (r *MemoryUserRepository) FindByEmail(email string) (*models.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, exists := r.emails[email]
+	if !exists {
+		return nil, false
+	}
+	return r.users[id], true
+}
+
+func (r *MemoryUserRepository) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.users)
+}
+
+func (r *MemoryUserRepository) MaxID() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	maxID := 0
+	for id := range r.users {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	return maxID
+}
+
+// Save stores the user. Emails must be unique; the service checks before saving.
+func (r *MemoryUserRepository) Save(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, exists := r.emails[user.Email]; exists && id != user.ID {
+		return fmt.Errorf("email %s already in use", user.Email)
+	}
+	if old, exists := r.users[user.ID]; exists && old.Email != user.Email {
+		delete(r.emails, old.Email)
+	}
+	r.users[user.ID] = user
+	r.emails[user.Email] = user.ID
+	return nil
+}
+
+func (r *MemoryUserRepository) findAll() []*models.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
+
+// ============================================
+// FILE-BACKED
+// ============================================
+type FileUserRepository struct {
+	*MemoryUserRepository
+	journal *journal
+}
+
+func NewFileUserRepository(path string) (*FileUserRepository, error) {
+	memory := NewMemoryUserRepository()
+
+	j, err := openJournal(path, func(entry journalEntry) error {
+		if entry.Op != journalOpPut {
+			return nil
+		}
+		var record userRecord
+		if err := json.Unmarshal(entry.Data, &record); err != nil {
+			return err
+		}
+		user := record.User
+		user.PasswordHash = record.PasswordHash
+		return memory.Save(&user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileUserRepository{MemoryUserRepository: memory, journal: j}
+	if err := r.compact(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileUserRepository) Save(user *models.User) error {
+	if err := r.MemoryUserRepository.Save(user); err != nil {
+		return err
+	}
+	return r.journal.put(strconv.Itoa(user.ID), userRecord{User: *user, PasswordHash: user.PasswordHash})
+}
+
+func (r *FileUserRepository) compact() error {
+	return r.journal.compact(func(write func(string, interface{}) error) error {
+		for _, user := range r.findAll() {
+			if err := write(strconv.Itoa(user.ID), userRecord{User: *user, PasswordHash: user.PasswordHash}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FileUserRepository) Close() error {
+	return r.journal.Close()
+}
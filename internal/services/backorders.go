@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+// Backorders: checkout deducts the full quantity even when a product's
+// stock policy lets it sell beyond stock, so stock goes negative by what
+// is owed. Order lines remember how many of their units are still waiting
+// (OrderItem.Backordered). Once stock comes back, AllocateBackorders hands
+// it to the waiting lines, oldest order first.
+
+var (
+	ErrInvalidStockPolicy  = errors.New("invalid stock policy")
+	ErrReleaseDateRequired = errors.New("pre-order products need a release date")
+	ErrOrderNotReady       = errors.New("order has backordered or unreleased items")
+)
+
+// checkStockPolicy validates a stock policy for the product named name
+// without changing anything.
+func checkStockPolicy(name string, policy models.StockPolicy, releaseDate *time.Time) error {
+	if !policy.IsValid() {
+		return fmt.Errorf("%w %q for product %q", ErrInvalidStockPolicy, policy, name)
+	}
+	if policy == models.PolicyPreorder && releaseDate == nil {
+		return fmt.Errorf("%w: product %q", ErrReleaseDateRequired, name)
+	}
+	return nil
+}
+
+// applyStockPolicy validates and sets a product's stock policy. Only
+// pre-orders keep a release date.
+func applyStockPolicy(product *models.Product, policy models.StockPolicy, limit int, releaseDate *time.Time) error {
+	if err := checkStockPolicy(product.Name, policy, releaseDate); err != nil {
+		return err
+	}
+	if policy != models.PolicyPreorder {
+		releaseDate = nil
+	}
+	product.StockPolicy = policy
+	product.BackorderLimit = limit
+	product.ReleaseDate = releaseDate
+	return nil
+}
+
+// preorderDate is the release date order lines of product wait for, or nil
+// when it is not an unreleased pre-order.
+func preorderDate(product *models.Product, now time.Time) *time.Time {
+	if product.StockPolicy != models.PolicyPreorder || product.ReleaseDate == nil || !now.Before(*product.ReleaseDate) {
+		return nil
+	}
+	releaseDate := *product.ReleaseDate
+	return &releaseDate
+}
+
+// markBackorders records on each order line how many of its units were
+// reserved beyond stock.
+func markBackorders(items []models.OrderItem, backordered map[models.SKURef]int) {
+	for i := range items {
+		ref := items[i].Ref()
+		units := min(backordered[ref], items[i].Quantity)
+		items[i].Backordered = units
+		backordered[ref] -= units
+	}
+}
+
+// Restocked signals when some SKU's stock went up. Signals are coalesced:
+// a receiver learns that something changed, not what.
+func (s *ProductService) Restocked() <-chan struct{} {
+	return s.restocked
+}
+
+// Shortfalls returns how many units each SKU owes beyond its stock (units
+// held by carts count as taken). SKUs that no longer exist are left out.
+func (s *ProductService) Shortfalls(items []models.SKURef) map[models.SKURef]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shortfalls := make(map[models.SKURef]int, len(items))
+	for _, item := range items {
+		_, _, stock, err := s.unitLocked(item)
+		if err != nil {
+			continue
+		}
+		shortfalls[item] = max(-s.availableLocked("", item, stock), 0)
+	}
+	return shortfalls
+}
+
+// AllocateBackorders covers waiting order lines with stock that has come
+// in, oldest order first, and returns how many units it allocated. A SKU's
+// waiting lines are owed its total backordered units; whatever of that the
+// stock no longer falls short of is covered.
+func (s *OrderService) AllocateBackorders() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var waiting []*models.Order
+	owed := make(map[models.SKURef]int)
+	for _, order := range s.repo.FindAll() {
+		if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusProcessing {
+			continue
+		}
+		isWaiting := false
+		for _, item := range order.Items {
+			if item.Backordered > 0 {
+				owed[item.Ref()] += item.Backordered
+				isWaiting = true
+			}
+		}
+		if isWaiting {
+			waiting = append(waiting, order)
+		}
+	}
+	if len(waiting) == 0 {
+		return 0, nil
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		return newestOrderFirst(waiting[j], waiting[i])
+	})
+
+	items := make([]models.SKURef, 0, len(owed))
+	for item := range owed {
+		items = append(items, item)
+	}
+	covered := make(map[models.SKURef]int, len(items))
+	for item, shortfall := range s.productService.Shortfalls(items) {
+		covered[item] = max(owed[item]-shortfall, 0)
+	}
+
+	allocated := 0
+	now := time.Now()
+	for _, order := range waiting {
+		changed := false
+		for i := range order.Items {
+			line := &order.Items[i]
+			units := min(line.Backordered, covered[line.Ref()])
+			if units == 0 {
+				continue
+			}
+			line.Backordered -= units
+			covered[line.Ref()] -= units
+			allocated += units
+			changed = true
+
+			// Restocks received at a location ship from there
+			picked, err := s.productService.PickLocations(line.Ref(), units, &order.ShippingAddress)
+			if err != nil {
+				return allocated, err
+			}
+			line.Allocations = mergeLocations(line.Allocations, picked...)
+		}
+		if !changed {
+			continue
+		}
+		order.UpdatedAt = now
+		if err := s.repo.Save(order); err != nil {
+			return allocated, err
+		}
+	}
+	return allocated, nil
+}
+
+// StartBackorderAllocator allocates backorders whenever stock comes in,
+// and every interval to catch stock freed by expired cart holds, until ctx
+// is cancelled.
+func (s *OrderService) StartBackorderAllocator(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.productService.Restocked():
+			}
+			s.AllocateBackorders()
+		}
+	}()
+}
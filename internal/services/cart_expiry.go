@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+const (
+	// DefaultCartTTL is how long an active cart may sit without changes
+	// before it is abandoned.
+	DefaultCartTTL = 24 * time.Hour
+	// DefaultCartRetention is how long closed carts are kept, e.g. for the
+	// abandoned-cart report, before they are deleted.
+	DefaultCartRetention = 30 * 24 * time.Hour
+)
+
+// cartExpiry is guarded by CartService.mu, except listeners, which are
+// registered at startup.
+type cartExpiry struct {
+	ttl       time.Duration
+	retention time.Duration
+	listeners []func(*models.Cart)
+}
+
+func newCartExpiry() *cartExpiry {
+	return &cartExpiry{
+		ttl:       DefaultCartTTL,
+		retention: DefaultCartRetention,
+	}
+}
+
+// SetCartExpiry changes how long idle carts stay active and how long
+// closed carts are kept.
+func (s *CartService) SetCartExpiry(ttl, retention time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expiry.ttl = ttl
+	s.expiry.retention = retention
+}
+
+// OnCartAbandoned registers fn to be called for every cart ExpireCarts
+// abandons, e.g. to release the stock it holds. Register listeners before
+// starting the janitor.
+func (s *CartService) OnCartAbandoned(fn func(*models.Cart)) {
+	s.expiry.listeners = append(s.expiry.listeners, fn)
+}
+
+// ExpireCarts abandons active carts idle past the TTL and deletes closed
+// carts past the retention period. Idle carts without items are deleted
+// right away, there is nothing to win back. It returns how many carts were
+// abandoned and deleted.
+func (s *CartService) ExpireCarts(now time.Time) (abandoned, deleted int, err error) {
+	s.mu.Lock()
+
+	var closed []*models.Cart
+	for _, cart := range s.repo.FindAll() {
+		if cart.IsActive() {
+			if now.Sub(cart.UpdatedAt) < s.expiry.ttl {
+				continue
+			}
+			if len(cart.Items) > 0 {
+				if err = s.closeLocked(cart, models.CartStatusAbandoned, "", now); err != nil {
+					break
+				}
+				closed = append(closed, cart)
+				continue
+			}
+		} else if cart.ClosedAt == nil || now.Sub(*cart.ClosedAt) < s.expiry.retention {
+			continue
+		}
+		if err = s.repo.Delete(cart.ID); err != nil {
+			break
+		}
+		deleted++
+	}
+	s.mu.Unlock()
+
+	// Outside the lock, so listeners may call back into the service
+	for _, cart := range closed {
+		for _, listener := range s.expiry.listeners {
+			listener(cart)
+		}
+	}
+	return len(closed), deleted, err
+}
+
+// StartCartJanitor expires carts every interval until ctx is cancelled.
+func (s *CartService) StartCartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.ExpireCarts(now)
+			}
+		}
+	}()
+}
+
+// AbandonedCarts lists carts abandoned within [from, to), most recently
+// abandoned first; a zero bound is open. It returns one page plus the
+// number and total value of all matching carts.
+func (s *CartService) AbandonedCarts(from, to time.Time, page, limit int) ([]models.AbandonedCart, int, float64) {
+	s.mu.RLock()
+	var carts []*models.Cart
+	for _, cart := range s.repo.FindAll() {
+		if cart.Status != models.CartStatusAbandoned || cart.ClosedAt == nil {
+			continue
+		}
+		if (!from.IsZero() && cart.ClosedAt.Before(from)) || (!to.IsZero() && !cart.ClosedAt.Before(to)) {
+			continue
+		}
+		carts = append(carts, cart)
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(carts, func(i, j int) bool {
+		return carts[i].ClosedAt.After(*carts[j].ClosedAt)
+	})
+
+	var value float64
+	for _, cart := range carts {
+		value += cart.Value()
+	}
+
+	start := min((page-1)*limit, len(carts))
+	end := min(start+limit, len(carts))
+	report := make([]models.AbandonedCart, 0, end-start)
+	for _, cart := range carts[start:end] {
+		entry := models.AbandonedCart{
+			CartID:       cart.ID,
+			UserID:       cart.UserID,
+			Items:        cart.Items,
+			Value:        cart.Value(),
+			LastActivity: cart.UpdatedAt,
+			AbandonedAt:  *cart.ClosedAt,
+		}
+		for _, item := range cart.Items {
+			entry.Units += item.Quantity
+		}
+		if user, exists := s.userService.GetUser(cart.UserID); exists {
+			entry.Email = user.Email
+			entry.Name = user.Name
+		}
+		report = append(report, entry)
+	}
+	return report, len(carts), value
+}
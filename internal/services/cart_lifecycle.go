@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+// Cart lifecycle: a cart is active until checkout converts it into an order
+// or it sits idle long enough to be abandoned (see ExpireCarts). Closed
+// carts are kept as a record for a while but no longer change, and are not
+// the user's current cart.
+
+var (
+	ErrCartNotFound     = errors.New("cart not found")
+	ErrCartItemNotFound = errors.New("product not found in cart")
+	ErrCartClosed       = errors.New("cart is no longer active")
+)
+
+// checkOpen returns ErrCartClosed unless cart is active.
+func checkOpen(cart *models.Cart) error {
+	if cart.IsActive() {
+		return nil
+	}
+	if cart.Status == models.CartStatusConverted {
+		return fmt.Errorf("%w: already checked out as order %s", ErrCartClosed, cart.OrderID)
+	}
+	return fmt.Errorf("%w: cart was %s", ErrCartClosed, cart.Status)
+}
+
+// RemoveItem takes item out of the cart.
+func (s *CartService) RemoveItem(cartID string, item models.SKURef) (*models.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.openCartLocked(cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(cart.Items, func(existing models.CartItem) bool {
+		return existing.Ref() == item
+	})
+	if i < 0 {
+		return nil, ErrCartItemNotFound
+	}
+	cart.Items = slices.Delete(cart.Items, i, i+1)
+	cart.UpdatedAt = time.Now()
+	cart.Version++
+
+	return cart, s.repo.Save(cart)
+}
+
+// ClearCart empties the cart. It stays the user's active cart.
+func (s *CartService) ClearCart(cartID string) (*models.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.openCartLocked(cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.Items = []models.CartItem{}
+	cart.UpdatedAt = time.Now()
+	cart.Version++
+
+	return cart, s.repo.Save(cart)
+}
+
+// ConvertCart closes the cart for checkout as orderID. Only one checkout
+// can convert a cart; the others get ErrCartClosed.
+func (s *CartService) ConvertCart(cartID, orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.openCartLocked(cartID)
+	if err != nil {
+		return err
+	}
+	return s.closeLocked(cart, models.CartStatusConverted, orderID, time.Now())
+}
+
+// reopenCart undoes ConvertCart when the checkout that converted the cart
+// fails after all.
+func (s *CartService) reopenCart(cartID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, exists := s.repo.FindByID(cartID)
+	if !exists || cart.Status != models.CartStatusConverted {
+		return nil
+	}
+	cart.Status = models.CartStatusActive
+	cart.OrderID = ""
+	cart.ClosedAt = nil
+	cart.UpdatedAt = time.Now()
+	cart.Version++
+
+	return s.repo.Save(cart)
+}
+
+// openCartLocked finds an active cart. Caller holds s.mu.
+func (s *CartService) openCartLocked(cartID string) (*models.Cart, error) {
+	cart, exists := s.repo.FindByID(cartID)
+	if !exists {
+		return nil, ErrCartNotFound
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// closeLocked moves cart to status. UpdatedAt keeps the last shopper
+// activity. Caller holds s.mu.
+func (s *CartService) closeLocked(cart *models.Cart, status models.CartStatus, orderID string, now time.Time) error {
+	cart.Status = status
+	cart.OrderID = orderID
+	cart.ClosedAt = &now
+	cart.Version++
+
+	return s.repo.Save(cart)
+}
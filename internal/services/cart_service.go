@@ -2,51 +2,59 @@ package services
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
 	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
 )
 
 type CartService struct {
-	mu      sync.RWMutex
-	carts   map[string]*models.Cart // cart_id -> cart
-	userCarts map[int]string       // user_id -> cart_id
+	mu          sync.RWMutex
+	repo        repository.CartRepository
+	userService *UserService
+	expiry      *cartExpiry
 }
 
-func NewCartService() *CartService {
+func NewCartService(repo repository.CartRepository, userService *UserService) *CartService {
 	return &CartService{
-		carts:     make(map[string]*models.Cart),
-		userCarts: make(map[int]string),
+		repo:        repo,
+		userService: userService,
+		expiry:      newCartExpiry(),
 	}
 }
 
 // ============================================
 // VERSION 1: TANPA LOCK - RACE CONDITION BAKAL TERJADI!
 // ============================================
-func (s *CartService) AddToCartNoLock(cartID string, productID, quantity int, productPrice float64, productName string) (*models.Cart, error) {
-	cart, exists := s.carts[cartID]
+func (s *CartService) AddToCartNoLock(cartID string, item models.SKURef, quantity int, productPrice float64, productName string) (*models.Cart, error) {
+	cart, exists := s.repo.FindByID(cartID)
 	if !exists {
-		return nil, fmt.Errorf("cart not found")
+		return nil, ErrCartNotFound
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
 	}
 
 	// Simulate network/database delay
 	time.Sleep(time.Millisecond * 20)
 
 	// Check if product already in cart
-	for i, item := range cart.Items {
-		if item.ProductID == productID {
+	for i, existing := range cart.Items {
+		if existing.Ref() == item {
 			// VULNERABLE TO RACE CONDITION!
 			cart.Items[i].Quantity += quantity
 			cart.Items[i].Price = productPrice
 			cart.UpdatedAt = time.Now()
-			return cart, nil
+			return cart, s.repo.Save(cart)
 		}
 	}
 
 	// Add new item
 	cart.Items = append(cart.Items, models.CartItem{
-		ProductID: productID,
+		ProductID: item.ProductID,
+		SKU:       item.SKU,
 		Quantity:  quantity,
 		Price:     productPrice,
 		Name:      productName,
@@ -54,37 +62,41 @@ func (s *CartService) AddToCartNoLock(cartID string, productID, quantity int, pr
 	})
 	cart.UpdatedAt = time.Now()
 
-	return cart, nil
+	return cart, s.repo.Save(cart)
 }
 
 // ============================================
 // VERSION 2: DENGAN MUTEX LOCK - AMAN
 // ============================================
-func (s *CartService) AddToCartWithLock(cartID string, productID, quantity int, productPrice float64, productName string) (*models.Cart, error) {
+func (s *CartService) AddToCartWithLock(cartID string, item models.SKURef, quantity int, productPrice float64, productName string) (*models.Cart, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	cart, exists := s.carts[cartID]
+	cart, exists := s.repo.FindByID(cartID)
 	if !exists {
-		return nil, fmt.Errorf("cart not found")
+		return nil, ErrCartNotFound
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
 	}
 
 	// Simulate network/database delay
 	time.Sleep(time.Millisecond * 20)
 
 	// Check if product already in cart
-	for i, item := range cart.Items {
-		if item.ProductID == productID {
+	for i, existing := range cart.Items {
+		if existing.Ref() == item {
 			cart.Items[i].Quantity += quantity
 			cart.Items[i].Price = productPrice
 			cart.UpdatedAt = time.Now()
-			return cart, nil
+			return cart, s.repo.Save(cart)
 		}
 	}
 
 	// Add new item
 	cart.Items = append(cart.Items, models.CartItem{
-		ProductID: productID,
+		ProductID: item.ProductID,
+		SKU:       item.SKU,
 		Quantity:  quantity,
 		Price:     productPrice,
 		Name:      productName,
@@ -92,19 +104,22 @@ func (s *CartService) AddToCartWithLock(cartID string, productID, quantity int,
 	})
 	cart.UpdatedAt = time.Now()
 
-	return cart, nil
+	return cart, s.repo.Save(cart)
 }
 
 // ============================================
 // VERSION 3: OPTIMISTIC LOCKING - DATABASE STYLE
 // ============================================
-func (s *CartService) AddToCartOptimistic(cartID string, productID, quantity int, productPrice float64, productName string, version int) (*models.Cart, error) {
+func (s *CartService) AddToCartOptimistic(cartID string, item models.SKURef, quantity int, productPrice float64, productName string, version int) (*models.Cart, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	cart, exists := s.carts[cartID]
+	cart, exists := s.repo.FindByID(cartID)
 	if !exists {
-		return nil, fmt.Errorf("cart not found")
+		return nil, ErrCartNotFound
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
 	}
 
 	// Check version for optimistic locking
@@ -117,8 +132,8 @@ func (s *CartService) AddToCartOptimistic(cartID string, productID, quantity int
 
 	// Check if product already in cart
 	found := false
-	for i, item := range cart.Items {
-		if item.ProductID == productID {
+	for i, existing := range cart.Items {
+		if existing.Ref() == item {
 			cart.Items[i].Quantity += quantity
 			cart.Items[i].Price = productPrice
 			found = true
@@ -128,7 +143,8 @@ func (s *CartService) AddToCartOptimistic(cartID string, productID, quantity int
 
 	if !found {
 		cart.Items = append(cart.Items, models.CartItem{
-			ProductID: productID,
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
 			Quantity:  quantity,
 			Price:     productPrice,
 			Name:      productName,
@@ -139,99 +155,128 @@ func (s *CartService) AddToCartOptimistic(cartID string, productID, quantity int
 	cart.UpdatedAt = time.Now()
 	cart.Version++ // Increment version
 
-	return cart, nil
+	return cart, s.repo.Save(cart)
 }
 
 // ============================================
 // RACE CONDITION DEMO: Update Quantity
 // ============================================
-func (s *CartService) UpdateCartItemQuantityRace(cartID string, productID, quantity int) (*models.Cart, error) {
+func (s *CartService) UpdateCartItemQuantityRace(cartID string, item models.SKURef, quantity int) (*models.Cart, error) {
 	// NO LOCK - This will cause race condition!
-	cart, exists := s.carts[cartID]
+	cart, exists := s.repo.FindByID(cartID)
 	if !exists {
-		return nil, fmt.Errorf("cart not found")
+		return nil, ErrCartNotFound
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
 	}
 
 	// Simulate processing delay (makes race condition more likely)
 	time.Sleep(time.Millisecond * 30)
 
-	for i, item := range cart.Items {
-		if item.ProductID == productID {
+	for i, existing := range cart.Items {
+		if existing.Ref() == item {
 			// RACE CONDITION HERE!
 			// If two requests update at same time, one will be lost
-			cart.Items[i].Quantity = quantity
+			if quantity == 0 {
+				cart.Items = slices.Delete(cart.Items, i, i+1)
+			} else {
+				cart.Items[i].Quantity = quantity
+			}
 			cart.UpdatedAt = time.Now()
-			return cart, nil
+			return cart, s.repo.Save(cart)
 		}
 	}
 
-	return nil, fmt.Errorf("product not found in cart")
+	return nil, ErrCartItemNotFound
 }
 
 // ============================================
 // SAFE VERSION: With Lock
 // ============================================
-func (s *CartService) UpdateCartItemQuantitySafe(cartID string, productID, quantity int) (*models.Cart, error) {
+func (s *CartService) UpdateCartItemQuantitySafe(cartID string, item models.SKURef, quantity int) (*models.Cart, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	cart, exists := s.carts[cartID]
+	cart, exists := s.repo.FindByID(cartID)
 	if !exists {
-		return nil, fmt.Errorf("cart not found")
+		return nil, ErrCartNotFound
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
 	}
 
 	// Simulate processing delay
 	time.Sleep(time.Millisecond * 30)
 
-	for i, item := range cart.Items {
-		if item.ProductID == productID {
-			cart.Items[i].Quantity = quantity
+	for i, existing := range cart.Items {
+		if existing.Ref() == item {
+			if quantity == 0 {
+				cart.Items = slices.Delete(cart.Items, i, i+1)
+			} else {
+				cart.Items[i].Quantity = quantity
+			}
 			cart.UpdatedAt = time.Now()
-			return cart, nil
+			return cart, s.repo.Save(cart)
 		}
 	}
 
-	return nil, fmt.Errorf("product not found in cart")
+	return nil, ErrCartItemNotFound
 }
 
 // Helper methods
-func (s *CartService) CreateCart(userID int) *models.Cart {
+
+// CreateCart returns the user's active cart, or starts one if there is none.
+// created reports whether the cart is new; reusing a cart counts as
+// activity.
+func (s *CartService) CreateCart(userID int) (cart *models.Cart, created bool, err error) {
+	if !s.userService.Exists(userID) {
+		return nil, false, ErrUserNotFound
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A user shops from one cart at a time
+	if current, exists := s.repo.FindByUserID(userID); exists && current.IsActive() {
+		current.UpdatedAt = time.Now()
+		return current, false, s.repo.Save(current)
+	}
+
 	cartID := fmt.Sprintf("cart_%d_%d", userID, time.Now().UnixNano())
-	cart := &models.Cart{
+	cart = &models.Cart{
 		ID:        cartID,
 		UserID:    userID,
 		Items:     []models.CartItem{},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Version:   1,
+		Status:    models.CartStatusActive,
 	}
 
-	s.carts[cartID] = cart
-	s.userCarts[userID] = cartID
+	if err := s.repo.Save(cart); err != nil {
+		return nil, false, err
+	}
 
-	return cart
+	return cart, true, nil
 }
 
 func (s *CartService) GetCart(cartID string) (*models.Cart, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	cart, exists := s.carts[cartID]
-	return cart, exists
+	return s.repo.FindByID(cartID)
 }
 
+// GetCartByUserID returns the user's current cart, unless it has been
+// checked out or abandoned.
 func (s *CartService) GetCartByUserID(userID int) (*models.Cart, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	cartID, exists := s.userCarts[userID]
-	if !exists {
+	cart, exists := s.repo.FindByUserID(userID)
+	if !exists || !cart.IsActive() {
 		return nil, false
 	}
-
-	cart, exists := s.carts[cartID]
-	return cart, exists
+	return cart, true
 }
\ No newline at end of file
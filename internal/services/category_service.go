@@ -0,0 +1,377 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("category already exists")
+	ErrCategoryInUse    = errors.New("category still has subcategories or products")
+	ErrCategoryCycle    = errors.New("category cannot be moved under itself")
+	ErrSlugTaken        = errors.New("slug already in use")
+)
+
+// CategoryService owns the category tree. Product-facing changes (renames
+// and deletes that affect products) go through ProductService.
+type CategoryService struct {
+	mu     sync.RWMutex
+	repo   repository.CategoryRepository
+	nextID int
+}
+
+func NewCategoryService(repo repository.CategoryRepository) *CategoryService {
+	return &CategoryService{
+		repo:   repo,
+		nextID: repo.MaxID() + 1,
+	}
+}
+
+func (s *CategoryService) Create(req models.CreateCategoryRequest) (*models.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createLocked(req)
+}
+
+func (s *CategoryService) createLocked(req models.CreateCategoryRequest) (*models.Category, error) {
+	name := strings.TrimSpace(req.Name)
+	if req.ParentID != 0 {
+		if _, exists := s.repo.FindByID(req.ParentID); !exists {
+			return nil, fmt.Errorf("%w: parent %d", ErrCategoryNotFound, req.ParentID)
+		}
+	}
+	if _, exists := s.childLocked(req.ParentID, name); exists {
+		return nil, fmt.Errorf("%w: %q", ErrCategoryExists, name)
+	}
+
+	slug, err := s.slugLocked(req.Slug, name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	category := &models.Category{
+		ID:        s.nextID,
+		Name:      name,
+		Slug:      slug,
+		ParentID:  req.ParentID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Save(category); err != nil {
+		return nil, err
+	}
+	s.nextID++
+	return category, nil
+}
+
+// Update renames or moves a category. Callers that show category paths on
+// products must refresh them afterwards (see ProductService.UpdateCategory).
+func (s *CategoryService) Update(id int, req models.UpdateCategoryRequest) (*models.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	category, exists := s.repo.FindByID(id)
+	if !exists {
+		return nil, fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+	}
+
+	parentID := category.ParentID
+	if req.ParentID != nil {
+		parentID = *req.ParentID
+		// Walk up from the new parent; meeting id would make a loop
+		for ancestor := parentID; ancestor != 0; {
+			if ancestor == id {
+				return nil, ErrCategoryCycle
+			}
+			parent, exists := s.repo.FindByID(ancestor)
+			if !exists {
+				return nil, fmt.Errorf("%w: parent %d", ErrCategoryNotFound, ancestor)
+			}
+			ancestor = parent.ParentID
+		}
+	}
+
+	name := category.Name
+	if req.Name != "" {
+		name = strings.TrimSpace(req.Name)
+	}
+	if sibling, exists := s.childLocked(parentID, name); exists && sibling.ID != id {
+		return nil, fmt.Errorf("%w: %q", ErrCategoryExists, name)
+	}
+
+	if req.Slug != "" {
+		slug, err := s.slugLocked(req.Slug, name, id)
+		if err != nil {
+			return nil, err
+		}
+		category.Slug = slug
+	}
+	category.Name = name
+	category.ParentID = parentID
+	category.UpdatedAt = time.Now()
+
+	if err := s.repo.Save(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Delete removes a category without subcategories. The caller checks that
+// no products use it.
+func (s *CategoryService) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.repo.FindByID(id); !exists {
+		return fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+	}
+	for _, category := range s.repo.FindAll() {
+		if category.ParentID == id {
+			return fmt.Errorf("%w: %d", ErrCategoryInUse, id)
+		}
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *CategoryService) Get(id int) (*models.Category, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo.FindByID(id)
+}
+
+// Lookup finds a category by ID, slug or path, in that order.
+func (s *CategoryService) Lookup(ref string) (*models.Category, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		return s.repo.FindByID(id)
+	}
+	for _, category := range s.repo.FindAll() {
+		if category.Slug == ref {
+			return category, true
+		}
+	}
+
+	parentID := 0
+	var category *models.Category
+	for _, name := range splitCategoryPath(ref) {
+		child, exists := s.childLocked(parentID, name)
+		if !exists {
+			return nil, false
+		}
+		category, parentID = child, child.ID
+	}
+	return category, category != nil
+}
+
+// Resolve returns the category at path ("Electronics > Audio"), creating
+// any missing levels.
+func (s *CategoryService) Resolve(path string) (*models.Category, error) {
+	names := splitCategoryPath(path)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: empty path", ErrCategoryNotFound)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var category *models.Category
+	parentID := 0
+	for _, name := range names {
+		child, exists := s.childLocked(parentID, name)
+		if !exists {
+			var err error
+			child, err = s.createLocked(models.CreateCategoryRequest{Name: name, ParentID: parentID})
+			if err != nil {
+				return nil, err
+			}
+		}
+		category, parentID = child, child.ID
+	}
+	return category, nil
+}
+
+// Path returns the names from the root down to id, joined with
+// models.CategoryPathSeparator.
+func (s *CategoryService) Path(id int) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pathLocked(id)
+}
+
+func (s *CategoryService) pathLocked(id int) string {
+	var names []string
+	for _, category := range s.ancestorsLocked(id) {
+		names = append(names, category.Name)
+	}
+	return strings.Join(names, models.CategoryPathSeparator)
+}
+
+// Ancestors returns the categories from the root down to id, inclusive.
+func (s *CategoryService) Ancestors(id int) []models.Category {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ancestorsLocked(id)
+}
+
+func (s *CategoryService) ancestorsLocked(id int) []models.Category {
+	var chain []models.Category
+	for id != 0 {
+		category, exists := s.repo.FindByID(id)
+		if !exists {
+			break
+		}
+		chain = append(chain, *category)
+		id = category.ParentID
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// Descendants returns id and the IDs of every category below it.
+func (s *CategoryService) Descendants(id int) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	children := make(map[int][]int)
+	for _, category := range s.repo.FindAll() {
+		children[category.ParentID] = append(children[category.ParentID], category.ID)
+	}
+
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// Tree returns the top-level categories with their subtrees, children
+// ordered by name.
+func (s *CategoryService) Tree() []*models.CategoryNode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.buildTreeLocked()[0].Children
+}
+
+// Subtree returns one category with everything below it.
+func (s *CategoryService) Subtree(id int) (*models.CategoryNode, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node, exists := s.buildTreeLocked()[id]
+	return node, exists && id != 0
+}
+
+// buildTreeLocked returns every node by ID; key 0 is a virtual root
+// holding the top-level categories.
+func (s *CategoryService) buildTreeLocked() map[int]*models.CategoryNode {
+	all := s.repo.FindAll()
+	nodes := make(map[int]*models.CategoryNode, len(all)+1)
+	nodes[0] = &models.CategoryNode{Children: []*models.CategoryNode{}}
+	for _, category := range all {
+		nodes[category.ID] = &models.CategoryNode{
+			Category: *category,
+			Path:     s.pathLocked(category.ID),
+			Children: []*models.CategoryNode{},
+		}
+	}
+	for _, category := range all {
+		if parent, exists := nodes[category.ParentID]; exists {
+			parent.Children = append(parent.Children, nodes[category.ID])
+		}
+	}
+	for _, node := range nodes {
+		sort.Slice(node.Children, func(i, j int) bool {
+			return node.Children[i].Name < node.Children[j].Name
+		})
+	}
+	return nodes
+}
+
+// childLocked finds the child of parentID named name, ignoring case.
+func (s *CategoryService) childLocked(parentID int, name string) (*models.Category, bool) {
+	for _, category := range s.repo.FindAll() {
+		if category.ParentID == parentID && strings.EqualFold(category.Name, name) {
+			return category, true
+		}
+	}
+	return nil, false
+}
+
+// slugLocked normalises a requested slug, which must be free. Without one
+// it derives a slug from name, adding a number until it is free. selfID is
+// the category being updated, whose own slug counts as free.
+func (s *CategoryService) slugLocked(requested, name string, selfID int) (string, error) {
+	taken := make(map[string]bool)
+	for _, category := range s.repo.FindAll() {
+		if category.ID != selfID {
+			taken[category.Slug] = true
+		}
+	}
+
+	if requested != "" {
+		slug := slugify(requested)
+		if taken[slug] {
+			return "", fmt.Errorf("%w: %s", ErrSlugTaken, slug)
+		}
+		return slug, nil
+	}
+
+	base := slugify(name)
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// slugify lower-cases text and joins runs of letters and digits with
+// hyphens: "Home & Garden" -> "home-garden".
+func slugify(text string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(text) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+		} else {
+			pendingHyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return "category"
+	}
+	return b.String()
+}
+
+// splitCategoryPath splits "Electronics > Audio" into its names.
+func splitCategoryPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, ">") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
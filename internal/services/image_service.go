@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-ecommerce/internal/media"
+	"go-ecommerce/internal/models"
+)
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrTooManyImages = errors.New("product already has the maximum number of images")
+	ErrImageOrder    = errors.New("image_ids must list every image of the product once")
+)
+
+const (
+	// MaxImageBytes is the largest upload accepted
+	MaxImageBytes    = 10 << 20
+	MaxProductImages = 20
+	ThumbnailSize    = 320
+)
+
+// ImageService stores product image files and thumbnails. The image list
+// itself lives on the product and is changed through ProductService.
+type ImageService struct {
+	products *ProductService
+	storage  media.Storage
+}
+
+func NewImageService(products *ProductService, storage media.Storage) *ImageService {
+	return &ImageService{
+		products: products,
+		storage:  storage,
+	}
+}
+
+// Upload stores an image with a thumbnail and appends it to the product's
+// images.
+func (s *ImageService) Upload(productID int, data []byte, alt string) (*models.ProductImage, error) {
+	if product, exists := s.products.GetProductByID(productID); !exists || product.IsDeleted() {
+		return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+
+	img, format, err := media.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var thumb bytes.Buffer
+	thumbFormat, err := media.EncodeThumbnail(&thumb, media.Thumbnail(img, ThumbnailSize), format)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newImageID()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("products/%d/%s%s", productID, id, media.Extension(format))
+	thumbKey := fmt.Sprintf("products/%d/%s_thumb%s", productID, id, media.Extension(thumbFormat))
+
+	if err := s.storage.Put(key, bytes.NewReader(data), media.ContentType(format)); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(thumbKey, &thumb, media.ContentType(thumbFormat)); err != nil {
+		s.storage.Delete(key)
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	image, err := s.products.AddImage(productID, models.ProductImage{
+		ID:           id,
+		URL:          s.storage.URL(key),
+		ThumbnailURL: s.storage.URL(thumbKey),
+		Key:          key,
+		ThumbnailKey: thumbKey,
+		Alt:          alt,
+		ContentType:  media.ContentType(format),
+		Width:        bounds.Dx(),
+		Height:       bounds.Dy(),
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		s.storage.Delete(key)
+		s.storage.Delete(thumbKey)
+		return nil, err
+	}
+	return image, nil
+}
+
+// Delete removes an image from the product, then its files. If removing
+// the files fails the image is already gone from the product.
+func (s *ImageService) Delete(productID int, imageID string) error {
+	image, err := s.products.RemoveImage(productID, imageID)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.Delete(image.Key); err != nil {
+		return err
+	}
+	return s.storage.Delete(image.ThumbnailKey)
+}
+
+func newImageID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ============================================
+// PRODUCT IMAGE LIST
+// ============================================
+
+// The image slice is replaced rather than edited in place, so a product
+// being encoded by a reader never sees a half-updated list.
+
+func (s *ProductService) AddImage(productID int, image models.ProductImage) (*models.ProductImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.activeProductLocked(productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(product.Images) >= MaxProductImages {
+		return nil, fmt.Errorf("%w (%d)", ErrTooManyImages, MaxProductImages)
+	}
+
+	images := append(append([]models.ProductImage(nil), product.Images...), image)
+	if err := s.saveImagesLocked(product, images); err != nil {
+		return nil, err
+	}
+	return &product.Images[len(product.Images)-1], nil
+}
+
+// UpdateImage changes an image's alt text and/or moves it to another
+// position.
+func (s *ProductService) UpdateImage(productID int, imageID string, req models.UpdateImageRequest) (*models.ProductImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.activeProductLocked(productID)
+	if err != nil {
+		return nil, err
+	}
+	from := imageIndex(product.Images, imageID)
+	if from < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, imageID)
+	}
+
+	image := product.Images[from]
+	if req.Alt != nil {
+		image.Alt = *req.Alt
+	}
+	to := from
+	if req.Position != nil {
+		to = min(*req.Position, len(product.Images)-1)
+	}
+
+	images := make([]models.ProductImage, 0, len(product.Images))
+	for i, other := range product.Images {
+		if i != from {
+			images = append(images, other)
+		}
+	}
+	images = append(images[:to], append([]models.ProductImage{image}, images[to:]...)...)
+
+	if err := s.saveImagesLocked(product, images); err != nil {
+		return nil, err
+	}
+	return &product.Images[to], nil
+}
+
+// ReorderImages puts the images in the order of imageIDs, which must list
+// each of the product's images exactly once.
+func (s *ProductService) ReorderImages(productID int, imageIDs []string) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.activeProductLocked(productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(imageIDs) != len(product.Images) {
+		return nil, fmt.Errorf("%w: got %d ids for %d images", ErrImageOrder, len(imageIDs), len(product.Images))
+	}
+
+	images := make([]models.ProductImage, 0, len(imageIDs))
+	seen := make(map[string]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		i := imageIndex(product.Images, id)
+		if i < 0 || seen[id] {
+			return nil, fmt.Errorf("%w: %s", ErrImageOrder, id)
+		}
+		seen[id] = true
+		images = append(images, product.Images[i])
+	}
+
+	if err := s.saveImagesLocked(product, images); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// RemoveImage drops an image from the product and returns it so the
+// caller can delete its files.
+func (s *ProductService) RemoveImage(productID int, imageID string) (*models.ProductImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.activeProductLocked(productID)
+	if err != nil {
+		return nil, err
+	}
+	i := imageIndex(product.Images, imageID)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, imageID)
+	}
+	removed := product.Images[i]
+
+	images := append(append([]models.ProductImage(nil), product.Images[:i]...), product.Images[i+1:]...)
+	if err := s.saveImagesLocked(product, images); err != nil {
+		return nil, err
+	}
+	return &removed, nil
+}
+
+func (s *ProductService) activeProductLocked(productID int) (*models.Product, error) {
+	product, exists := s.repo.FindByID(productID)
+	if !exists || product.IsDeleted() {
+		return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+	return product, nil
+}
+
+// saveImagesLocked renumbers images and stores them on the product.
+func (s *ProductService) saveImagesLocked(product *models.Product, images []models.ProductImage) error {
+	for i := range images {
+		images[i].Position = i
+	}
+	if len(images) == 0 {
+		images = nil
+	}
+	product.Images = images
+	product.UpdatedAt = time.Now()
+	return s.repo.Save(product)
+}
+
+func imageIndex(images []models.ProductImage, id string) int {
+	for i := range images {
+		if images[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
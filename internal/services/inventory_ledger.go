@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+// Every stock change goes through ProductService under s.mu and appends
+// one ledger entry per SKU it touches, so replaying the ledger gives the
+// current stock. The only exception is the deliberately unlocked order
+// path (CreateOrderNoLock), which records through RecordMovement; its lost
+// updates show up as drift and breaks in Reconcile.
+
+// stockLevels returns the stock of each SKU of a product; a product
+// without variants has the single SKU "".
+func stockLevels(product *models.Product) map[string]int {
+	if !product.HasVariants() {
+		return map[string]int{"": product.Stock}
+	}
+	levels := make(map[string]int, len(product.Variants))
+	for _, variant := range product.Variants {
+		levels[variant.SKU] = variant.Stock
+	}
+	return levels
+}
+
+// recordLocked appends a ledger entry for every SKU whose stock differs
+// from before (nil for a new product). SKUs missing from before are
+// recorded as MovementInitial. Caller holds s.mu.
+func (s *ProductService) recordLocked(product *models.Product, before map[string]int, change models.StockChange) error {
+	after := stockLevels(product)
+
+	skus := make([]string, 0, len(before)+len(after))
+	for sku := range after {
+		skus = append(skus, sku)
+	}
+	for sku := range before {
+		if _, exists := after[sku]; !exists {
+			skus = append(skus, sku)
+		}
+	}
+	sort.Strings(skus)
+
+	for _, sku := range skus {
+		old, existed := before[sku]
+		delta := after[sku] - old
+		if delta == 0 {
+			continue
+		}
+		entry := change
+		if !existed {
+			entry.Type = models.MovementInitial
+		}
+		item := models.SKURef{ProductID: product.ID, SKU: sku}
+		if err := s.appendMovementLocked(item, delta, after[sku], entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ProductService) appendMovementLocked(item models.SKURef, delta, quantity int, change models.StockChange) error {
+	if delta > 0 {
+		select {
+		case s.restocked <- struct{}{}:
+		default:
+		}
+	}
+	s.nextMovementID++
+	return s.ledger.Append(&models.StockMovement{
+		ID:        s.nextMovementID,
+		ProductID: item.ProductID,
+		SKU:       item.SKU,
+		Type:      change.Type,
+		Delta:     delta,
+		Quantity:  quantity,
+		Actor:     change.Actor,
+		Reason:    change.Reason,
+		Reference: change.Reference,
+		CreatedAt: time.Now(),
+	})
+}
+
+// RecordMovement appends a ledger entry for a stock change made outside
+// ProductService's lock. quantity is the stock the caller believes it left
+// behind.
+func (s *ProductService) RecordMovement(item models.SKURef, delta, quantity int, change models.StockChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.appendMovementLocked(item, delta, quantity, change)
+}
+
+// OpenLedger records the current stock of every SKU the ledger has never
+// seen, e.g. products stored before the ledger existed. It returns how
+// many entries it wrote.
+func (s *ProductService) OpenLedger() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[models.SKURef]bool)
+	for _, movement := range s.ledger.FindAll() {
+		seen[movement.Ref()] = true
+	}
+
+	opened := 0
+	change := models.StockChange{
+		Type:   models.MovementInitial,
+		Actor:  models.ActorSystem,
+		Reason: "opening balance",
+	}
+	for _, product := range s.repo.FindAll() {
+		for sku, stock := range stockLevels(product) {
+			item := models.SKURef{ProductID: product.ID, SKU: sku}
+			if seen[item] || stock == 0 {
+				continue
+			}
+			if err := s.appendMovementLocked(item, stock, stock, change); err != nil {
+				return opened, err
+			}
+			opened++
+		}
+	}
+	return opened, nil
+}
+
+// StockHistory returns a product's ledger entries newest first, optionally
+// only those of one SKU, with the total count before paging.
+func (s *ProductService) StockHistory(productID int, sku string, page, limit int) ([]models.StockMovement, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.repo.FindByID(productID); !exists {
+		return nil, 0, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+
+	var movements []*models.StockMovement
+	for _, movement := range s.ledger.FindByProduct(productID) {
+		if sku == "" || movement.SKU == sku {
+			movements = append(movements, movement)
+		}
+	}
+	total := len(movements)
+
+	start := (page - 1) * limit
+	history := make([]models.StockMovement, 0, limit)
+	for i := total - 1 - start; i >= 0 && len(history) < limit; i-- {
+		history = append(history, *movements[i])
+	}
+	return history, total, nil
+}
+
+// Reconcile replays the ledger and compares it with current stock, per
+// SKU, for one product or for all of them (productID 0). SKUs that no
+// longer exist count as having no stock.
+func (s *ProductService) Reconcile(productID int) []models.StockReconciliation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var products []*models.Product
+	var movements []*models.StockMovement
+	if productID != 0 {
+		if product, exists := s.repo.FindByID(productID); exists {
+			products = append(products, product)
+		}
+		movements = s.ledger.FindByProduct(productID)
+	} else {
+		products = s.repo.FindAll()
+		movements = s.ledger.FindAll()
+	}
+
+	results := make(map[models.SKURef]*models.StockReconciliation)
+	entry := func(item models.SKURef) *models.StockReconciliation {
+		result, exists := results[item]
+		if !exists {
+			result = &models.StockReconciliation{ProductID: item.ProductID, SKU: item.SKU}
+			results[item] = result
+		}
+		return result
+	}
+
+	last := make(map[models.SKURef]int)
+	for _, movement := range movements {
+		item := movement.Ref()
+		result := entry(item)
+		if last[item]+movement.Delta != movement.Quantity {
+			result.Breaks++
+		}
+		last[item] = movement.Quantity
+		result.LedgerStock += movement.Delta
+		result.Movements++
+	}
+	for _, product := range products {
+		for sku, stock := range stockLevels(product) {
+			entry(models.SKURef{ProductID: product.ID, SKU: sku}).ActualStock = stock
+		}
+	}
+
+	report := make([]models.StockReconciliation, 0, len(results))
+	for _, result := range results {
+		result.Drift = result.ActualStock - result.LedgerStock
+		report = append(report, *result)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].ProductID != report[j].ProductID {
+			return report[i].ProductID < report[j].ProductID
+		}
+		return report[i].SKU < report[j].SKU
+	})
+	return report
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrInvalidQuantity   = errors.New("quantity must be greater than zero")
+	ErrTxClosed          = errors.New("inventory transaction already committed or aborted")
+	ErrSKURequired       = errors.New("product has variants, a sku is required")
+	ErrSKUNotFound       = errors.New("sku not found")
+	ErrDuplicateSKU      = errors.New("duplicate sku")
+)
+
+// InventoryTx groups stock reservations so a multi-item order deducts
+// every line or none of them.
+//
+// Reserve deducts stock immediately, so concurrent transactions never see
+// units that are already promised. Abort compensates by putting every
+// reserved unit back; Commit makes the deductions final.
+//
+// Units held by carts are not available, except a checkout transaction's
+// own cart holds, which it converts into deductions.
+//
+// Products whose stock policy takes backorders can be reserved beyond
+// what is available; Backordered reports those units. SKUs that track
+// locations are taken from warehouses ranked for the ShipTo address;
+// Allocations reports where from.
+type InventoryTx struct {
+	productService *ProductService
+	cartID         string             // checkout transactions only
+	change         models.StockChange // recorded in the ledger
+	shipTo         *models.ShippingAddress
+
+	mu          sync.Mutex
+	reserved    map[models.SKURef]int                       // quantity deducted per SKU
+	converted   map[models.SKURef]int                       // part of reserved taken from the cart's holds
+	backordered map[models.SKURef]int                       // part of reserved beyond available stock
+	allocated   map[models.SKURef][]models.LocationQuantity // part of reserved taken from locations
+	closed      bool
+}
+
+// BeginInventoryTx starts a new inventory transaction. Its deductions are
+// recorded in the ledger as change.
+func (s *ProductService) BeginInventoryTx(change models.StockChange) *InventoryTx {
+	return s.BeginCheckoutTx("", change)
+}
+
+// BeginCheckoutTx starts an inventory transaction for checking out
+// cartID: the cart's holds count as available and are used up by Reserve.
+// Abort gives them back.
+func (s *ProductService) BeginCheckoutTx(cartID string, change models.StockChange) *InventoryTx {
+	return &InventoryTx{
+		productService: s,
+		cartID:         cartID,
+		change:         change,
+		reserved:       make(map[models.SKURef]int),
+		converted:      make(map[models.SKURef]int),
+		backordered:    make(map[models.SKURef]int),
+		allocated:      make(map[models.SKURef][]models.LocationQuantity),
+	}
+}
+
+// ShipTo sets the address locations are ranked for. Call it before
+// reserving.
+func (tx *InventoryTx) ShipTo(address models.ShippingAddress) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.shipTo = &address
+}
+
+// Reserve deducts quantity units of one SKU.
+func (tx *InventoryTx) Reserve(item models.SKURef, quantity int) error {
+	return tx.ReserveMany(map[models.SKURef]int{item: quantity})
+}
+
+// ReserveMany deducts all quantities atomically: either every SKU has
+// enough stock (or may be backordered) and all are deducted, or nothing
+// changes.
+func (tx *InventoryTx) ReserveMany(quantities map[models.SKURef]int) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	s := tx.productService
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check all SKUs have enough stock
+	available := make(map[models.SKURef]int, len(quantities))
+	for item, quantity := range quantities {
+		if quantity <= 0 {
+			return ErrInvalidQuantity
+		}
+		product, name, stock, err := s.unitLocked(item)
+		if err != nil {
+			return err
+		}
+		available[item] = s.availableLocked(tx.cartID, item, stock)
+		if !product.Backorderable(available[item], quantity) {
+			return fmt.Errorf("%w for product %s", ErrInsufficientStock, name)
+		}
+	}
+
+	// Deduct, undoing this call's own deductions if a write fails
+	ranked := s.rankLocationsLocked(tx.shipTo, quantities)
+	applied := make(map[models.SKURef]int, len(quantities))
+	plans := make(map[models.SKURef][]models.LocationQuantity, len(quantities))
+	for item, quantity := range quantities {
+		product, _ := s.repo.FindByID(item.ProductID)
+		plan := planLocations(product, item.SKU, quantity, ranked)
+		if err := s.adjustLocatedLocked(item, -quantity, negated(plan), tx.change); err != nil {
+			for done, qty := range applied {
+				s.adjustLocatedLocked(done, qty, plans[done], tx.undoChange())
+			}
+			return err
+		}
+		applied[item] = quantity
+		plans[item] = plan
+	}
+
+	now := time.Now()
+	for item, quantity := range applied {
+		tx.reserved[item] += quantity
+		tx.allocated[item] = mergeLocations(tx.allocated[item], plans[item]...)
+		if short := quantity - max(available[item], 0); short > 0 {
+			tx.backordered[item] += short
+		}
+		if held := s.heldLocked(tx.cartID, item); held > 0 {
+			used := min(held, quantity)
+			s.holds.set(tx.cartID, item, held-used, now)
+			tx.converted[item] += used
+		}
+	}
+	return nil
+}
+
+// Commit makes the reservations final.
+func (tx *InventoryTx) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTxClosed
+	}
+	tx.closed = true
+	return nil
+}
+
+// Abort returns every reserved unit to stock. Aborting a closed
+// transaction is a no-op, so it is safe to defer.
+func (tx *InventoryTx) Abort() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return nil
+	}
+	tx.closed = true
+
+	s := tx.productService
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for item, quantity := range tx.reserved {
+		if err := s.adjustLocatedLocked(item, quantity, tx.allocated[item], tx.undoChange()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	now := time.Now()
+	for item, quantity := range tx.converted {
+		s.holds.set(tx.cartID, item, s.heldLocked(tx.cartID, item)+quantity, now)
+	}
+	tx.reserved = nil
+	tx.converted = nil
+	tx.backordered = nil
+	tx.allocated = nil
+	return firstErr
+}
+
+// Backordered returns, per SKU, how many of the reserved units were not in
+// stock and have to wait for a restock.
+func (tx *InventoryTx) Backordered() map[models.SKURef]int {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	backordered := make(map[models.SKURef]int, len(tx.backordered))
+	for item, quantity := range tx.backordered {
+		backordered[item] = quantity
+	}
+	return backordered
+}
+
+// Allocations returns, per SKU, how many reserved units each location
+// gives.
+func (tx *InventoryTx) Allocations() map[models.SKURef][]models.LocationQuantity {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	allocations := make(map[models.SKURef][]models.LocationQuantity, len(tx.allocated))
+	for item, parts := range tx.allocated {
+		allocations[item] = append([]models.LocationQuantity(nil), parts...)
+	}
+	return allocations
+}
+
+// undoChange describes putting reserved stock back.
+func (tx *InventoryTx) undoChange() models.StockChange {
+	change := tx.change
+	change.Reason = "reservation released"
+	return change
+}
+
+// adjustStockLocked adds delta to a SKU's stock and records it in the
+// ledger. Caller holds s.mu.
+func (s *ProductService) adjustStockLocked(item models.SKURef, delta int, change models.StockChange) error {
+	return s.adjustLocatedLocked(item, delta, nil, change)
+}
+
+// adjustLocatedLocked is adjustStockLocked where parts of delta (same
+// sign) are at locations; the rest is stock not placed in any. Caller
+// holds s.mu.
+func (s *ProductService) adjustLocatedLocked(item models.SKURef, delta int, locations []models.LocationQuantity, change models.StockChange) error {
+	product, exists := s.repo.FindByID(item.ProductID)
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+	}
+	if !product.AddStock(item.SKU, delta) {
+		return skuError(product, item.SKU)
+	}
+	for _, part := range locations {
+		product.AddLocationStock(item.SKU, part.Location, part.Quantity)
+	}
+
+	product.UpdatedAt = time.Now()
+	if err := s.repo.Save(product); err != nil {
+		return err
+	}
+	_, _, stock, _ := product.Unit(item.SKU)
+	return s.appendMovementLocked(item, delta, stock, change)
+}
+
+// unitLocked looks up a purchasable SKU: the product must exist and not be
+// deleted, and the SKU must fit it (see models.Product.Unit). Caller holds
+// s.mu.
+func (s *ProductService) unitLocked(item models.SKURef) (product *models.Product, name string, stock int, err error) {
+	product, exists := s.repo.FindByID(item.ProductID)
+	if !exists || product.IsDeleted() {
+		return nil, "", 0, fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+	}
+	name, _, stock, ok := product.Unit(item.SKU)
+	if !ok {
+		return nil, "", 0, skuError(product, item.SKU)
+	}
+	return product, name, stock, nil
+}
+
+// skuError explains why sku does not fit product.
+func skuError(product *models.Product, sku string) error {
+	if sku == "" {
+		return fmt.Errorf("%w: product %d", ErrSKURequired, product.ID)
+	}
+	return fmt.Errorf("%w: %q for product %d", ErrSKUNotFound, sku, product.ID)
+}
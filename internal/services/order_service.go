@@ -1,19 +1,27 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrOrderForbidden    = errors.New("order belongs to another user")
+	ErrInvalidTransition = errors.New("invalid order status transition")
 )
 
 type OrderService struct {
 	mu           sync.RWMutex
-	orders       map[string]*models.Order // order_id -> order
-	userOrders   map[int][]string         // user_id -> order_ids
+	repo         repository.OrderRepository
 	productService *ProductService
 	cartService   *CartService
+	userService   *UserService
 	
 	// Statistics for monitoring
 	stats struct {
@@ -24,24 +32,31 @@ type OrderService struct {
 	}
 }
 
-func NewOrderService(productService *ProductService, cartService *CartService) *OrderService {
+func NewOrderService(repo repository.OrderRepository, productService *ProductService, cartService *CartService, userService *UserService) *OrderService {
 	return &OrderService{
-		orders:        make(map[string]*models.Order),
-		userOrders:    make(map[int][]string),
+		repo:          repo,
 		productService: productService,
 		cartService:   cartService,
+		userService:   userService,
 	}
 }
 
 // ============================================
 // VERSION 1: DANGEROUS - NO INVENTORY LOCK
 // ============================================
-func (s *OrderService) CreateOrderNoLock(cartID string, userID int, address, paymentMethod string) (*models.Order, error) {
+func (s *OrderService) CreateOrderNoLock(cartID string, userID int, address models.ShippingAddress, payment models.PaymentDetails) (*models.Order, error) {
+	if !s.userService.Exists(userID) {
+		return nil, ErrUserNotFound
+	}
+
 	// Get cart
 	cart, exists := s.cartService.GetCart(cartID)
 	if !exists {
 		return nil, fmt.Errorf("cart not found")
 	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
+	}
 
 	// Check cart belongs to user
 	if cart.UserID != userID {
@@ -55,54 +70,70 @@ func (s *OrderService) CreateOrderNoLock(cartID string, userID int, address, pay
 
 	var total float64
 	var orderItems []models.OrderItem
+	orderID := fmt.Sprintf("order_%d_%d", userID, time.Now().UnixNano())
+	change := models.StockChange{Type: models.MovementOrder, Actor: models.UserActor(userID), Reference: orderID}
 
 	// Process each item - RACE CONDITION DANGER ZONE!
 	for _, item := range cart.Items {
 		// Get current product info
 		product, exists := s.productService.GetProductByID(item.ProductID)
-		if !exists {
+		if !exists || product.IsDeleted() {
 			return nil, fmt.Errorf("product %d not found", item.ProductID)
 		}
+		name, price, stock, ok := product.Unit(item.SKU)
+		if !ok {
+			return nil, skuError(product, item.SKU)
+		}
 
 		// Check stock WITHOUT LOCK - RACE CONDITION!
-		if product.Stock < item.Quantity {
-			return nil, fmt.Errorf("insufficient stock for product %s", product.Name)
+		if stock < item.Quantity {
+			return nil, fmt.Errorf("insufficient stock for product %s", name)
 		}
 
 		// Simulate inventory check delay
 		time.Sleep(time.Millisecond * 25)
 
 		// Deduct stock WITHOUT LOCK - ANOTHER RACE CONDITION!
-		product.Stock -= item.Quantity
+		product.AddStock(item.SKU, -item.Quantity)
+		// The ledger gets the stock this request believes it left, so lost
+		// updates show up in reconciliation
+		if err := s.productService.RecordMovement(item.Ref(), -item.Quantity, stock-item.Quantity, change); err != nil {
+			return nil, err
+		}
 
 		// Add to order items
 		orderItems = append(orderItems, models.OrderItem{
 			ProductID: item.ProductID,
+			SKU:       item.SKU,
 			Quantity:  item.Quantity,
-			Price:     product.Price,
-			Name:      product.Name,
+			Price:     price,
+			Name:      name,
 		})
 
-		total += product.Price * float64(item.Quantity)
+		total += price * float64(item.Quantity)
 	}
 
 	// Create order
-	orderID := fmt.Sprintf("order_%d_%d", userID, time.Now().UnixNano())
 	order := &models.Order{
 		ID:           orderID,
 		UserID:       userID,
 		Items:        orderItems,
 		Total:        total,
 		Status:       models.OrderStatusPending,
+		ShippingAddress: address,
+		Payment:      payment,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	s.mu.Lock()
-	s.orders[orderID] = order
-	s.userOrders[userID] = append(s.userOrders[userID], orderID)
-	s.stats.totalOrders++
-	s.mu.Unlock()
+	if err := s.saveOrder(order); err != nil {
+		return nil, err
+	}
+	s.productService.ReleaseHolds(cartID)
+
+	// Close the cart WITHOUT claiming it first - two requests can still
+	// both check it out!
+	s.cartService.ConvertCart(cartID, orderID)
 
 	return order, nil
 }
@@ -110,12 +141,19 @@ func (s *OrderService) CreateOrderNoLock(cartID string, userID int, address, pay
 // ============================================
 // VERSION 2: SAFE WITH DISTRIBUTED LOCK PATTERN
 // ============================================
-func (s *OrderService) CreateOrderSafe(cartID string, userID int, address, paymentMethod string) (*models.Order, error) {
+func (s *OrderService) CreateOrderSafe(cartID string, userID int, address models.ShippingAddress, payment models.PaymentDetails) (*models.Order, error) {
+	if !s.userService.Exists(userID) {
+		return nil, ErrUserNotFound
+	}
+
 	// Get cart
 	cart, exists := s.cartService.GetCart(cartID)
 	if !exists {
 		return nil, fmt.Errorf("cart not found")
 	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
+	}
 
 	// Check cart belongs to user
 	if cart.UserID != userID {
@@ -143,73 +181,109 @@ func (s *OrderService) CreateOrderSafe(cartID string, userID int, address, payme
 	var total float64
 	var orderItems []models.OrderItem
 	var productsToUpdate []struct {
-		productID int
-		quantity  int
+		item     models.SKURef
+		quantity int
 	}
 
 	// Step 1: Validate inventory with locks
 	for _, item := range cart.Items {
 		product, exists := s.productService.GetProductByID(item.ProductID)
-		if !exists {
+		if !exists || product.IsDeleted() {
 			return nil, fmt.Errorf("product %d not found", item.ProductID)
 		}
+		name, price, stock, ok := product.Unit(item.SKU)
+		if !ok {
+			return nil, skuError(product, item.SKU)
+		}
 
 		// Check stock with proper locking at service level
 		// In real app, this would be a database transaction
-		if product.Stock < item.Quantity {
+		if !product.Backorderable(stock, item.Quantity) {
 			s.stats.Lock()
 			s.stats.failedOrders++
 			s.stats.Unlock()
-			return nil, fmt.Errorf("insufficient stock for product %s", product.Name)
+			return nil, fmt.Errorf("insufficient stock for product %s", name)
 		}
 
 		productsToUpdate = append(productsToUpdate, struct {
-			productID int
-			quantity  int
+			item     models.SKURef
+			quantity int
 		}{
-			productID: item.ProductID,
-			quantity:  item.Quantity,
+			item:     item.Ref(),
+			quantity: item.Quantity,
 		})
 
 		orderItems = append(orderItems, models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     product.Price,
-			Name:      product.Name,
+			ProductID:   item.ProductID,
+			SKU:         item.SKU,
+			Quantity:    item.Quantity,
+			Price:       price,
+			Name:        name,
+			ReleaseDate: preorderDate(product, time.Now()),
 		})
 
-		total += product.Price * float64(item.Quantity)
+		total += price * float64(item.Quantity)
 	}
 
-	// Step 2: Update inventory (simulated atomic operation)
-	for _, update := range productsToUpdate {
-		success, err := s.productService.UpdateStock(update.productID, update.quantity)
-		if !success || err != nil {
-			// Rollback previous updates would go here
-			return nil, fmt.Errorf("failed to update inventory for product %d", update.productID)
+	// Step 2: Close the cart, so only this request checks it out
+	orderID := fmt.Sprintf("order_%d_%d", userID, time.Now().UnixNano())
+	if err := s.cartService.ConvertCart(cartID, orderID); err != nil {
+		return nil, err
+	}
+	placed := false
+	defer func() {
+		if !placed {
+			s.cartService.reopenCart(cartID)
 		}
+	}()
+
+	// Step 3: Reserve inventory - all lines or none, using up the cart's holds
+	tx := s.productService.BeginCheckoutTx(cartID, models.StockChange{
+		Type:      models.MovementOrder,
+		Actor:     models.UserActor(userID),
+		Reference: orderID,
+	})
+	defer tx.Abort() // no-op once committed
+	tx.ShipTo(address)
+
+	// One call for every line, so the allocation strategy sees the whole order
+	quantities := make(map[models.SKURef]int, len(productsToUpdate))
+	for _, update := range productsToUpdate {
+		quantities[update.item] += update.quantity
+	}
+	if err := tx.ReserveMany(quantities); err != nil {
+		s.stats.Lock()
+		s.stats.failedOrders++
+		s.stats.Unlock()
+		return nil, fmt.Errorf("failed to update inventory: %w", err)
 	}
 
-	// Step 3: Create order
-	orderID := fmt.Sprintf("order_%d_%d", userID, time.Now().UnixNano())
+	// Lines short of stock wait for a restock
+	markBackorders(orderItems, tx.Backordered())
+	markAllocations(orderItems, tx.Allocations())
+
+	// Step 4: Create order
 	order := &models.Order{
 		ID:           orderID,
 		UserID:       userID,
 		Items:        orderItems,
 		Total:        total,
 		Status:       models.OrderStatusPending,
+		ShippingAddress: address,
+		Payment:      payment,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	s.mu.Lock()
-	s.orders[orderID] = order
-	s.userOrders[userID] = append(s.userOrders[userID], orderID)
-	s.stats.totalOrders++
-	s.mu.Unlock()
+	if err := s.saveOrder(order); err != nil {
+		return nil, err
+	}
 
-	// Step 4: Clear cart (optional)
-	// s.cartService.ClearCart(cartID)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	placed = true
+	s.productService.ReleaseHolds(cartID)
 
 	return order, nil
 }
@@ -217,7 +291,11 @@ func (s *OrderService) CreateOrderSafe(cartID string, userID int, address, payme
 // ============================================
 // VERSION 3: BATCH INVENTORY CHECK & UPDATE
 // ============================================
-func (s *OrderService) CreateOrderBatchCheck(cartID string, userID int) (*models.Order, error) {
+func (s *OrderService) CreateOrderBatchCheck(cartID string, userID int, address models.ShippingAddress, payment models.PaymentDetails) (*models.Order, error) {
+	if !s.userService.Exists(userID) {
+		return nil, ErrUserNotFound
+	}
+
 	// This version tries to check all inventory at once
 	// then update all at once to minimize race window
 
@@ -225,98 +303,158 @@ func (s *OrderService) CreateOrderBatchCheck(cartID string, userID int) (*models
 	if !exists {
 		return nil, fmt.Errorf("cart not found")
 	}
+	if cart.UserID != userID {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	if err := checkOpen(cart); err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, fmt.Errorf("cart is empty")
+	}
 
-	// Build product quantity map
-	productQuantities := make(map[int]int)
+	// Build SKU quantity map
+	productQuantities := make(map[models.SKURef]int)
 	var orderItems []models.OrderItem
 	var total float64
 
 	for _, item := range cart.Items {
 		product, exists := s.productService.GetProductByID(item.ProductID)
-		if !exists {
+		if !exists || product.IsDeleted() {
 			return nil, fmt.Errorf("product %d not found", item.ProductID)
 		}
+		name, price, _, ok := product.Unit(item.SKU)
+		if !ok {
+			return nil, skuError(product, item.SKU)
+		}
 
-		productQuantities[item.ProductID] = item.Quantity
+		productQuantities[item.Ref()] = item.Quantity
 		
 		orderItems = append(orderItems, models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     product.Price,
-			Name:      product.Name,
+			ProductID:   item.ProductID,
+			SKU:         item.SKU,
+			Quantity:    item.Quantity,
+			Price:       price,
+			Name:        name,
+			ReleaseDate: preorderDate(product, time.Now()),
 		})
 
-		total += product.Price * float64(item.Quantity)
+		total += price * float64(item.Quantity)
+	}
+
+	// Claim the cart before touching stock
+	orderID := fmt.Sprintf("order_%d_%d", userID, time.Now().UnixNano())
+	if err := s.cartService.ConvertCart(cartID, orderID); err != nil {
+		return nil, err
 	}
+	placed := false
+	defer func() {
+		if !placed {
+			s.cartService.reopenCart(cartID)
+		}
+	}()
 
 	// Try to reserve inventory for all products
 	// This should be atomic in real database
-	success := s.tryReserveInventory(productQuantities)
+	tx, success := s.tryReserveInventory(cartID, address, productQuantities, models.StockChange{
+		Type:      models.MovementOrder,
+		Actor:     models.UserActor(userID),
+		Reference: orderID,
+	})
 	if !success {
 		s.stats.Lock()
 		s.stats.raceConditionDetected++
 		s.stats.Unlock()
 		return nil, fmt.Errorf("inventory reservation failed - possible race condition")
 	}
+	defer tx.Abort() // no-op once committed
+	markBackorders(orderItems, tx.Backordered())
+	markAllocations(orderItems, tx.Allocations())
 
 	// Create order
-	orderID := fmt.Sprintf("order_%d_%d", userID, time.Now().UnixNano())
 	order := &models.Order{
 		ID:        orderID,
 		UserID:    userID,
 		Items:     orderItems,
 		Total:     total,
 		Status:    models.OrderStatusPending,
+		ShippingAddress: address,
+		Payment:   payment,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	s.mu.Lock()
-	s.orders[orderID] = order
-	s.userOrders[userID] = append(s.userOrders[userID], orderID)
-	s.stats.totalOrders++
-	s.mu.Unlock()
+	// Stock only stays taken once the order exists
+	if err := s.saveOrder(order); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	placed = true
+	s.productService.ReleaseHolds(cartID)
 
 	return order, nil
 }
 
-func (s *OrderService) tryReserveInventory(productQuantities map[int]int) bool {
-	// Simulate atomic inventory reservation
+// tryReserveInventory returns the open transaction holding the reserved
+// units, for its Backordered and Allocations. The caller commits or aborts
+// it.
+func (s *OrderService) tryReserveInventory(cartID string, address models.ShippingAddress, productQuantities map[models.SKURef]int, change models.StockChange) (*InventoryTx, bool) {
+	// Atomic inventory reservation
 	// In real app: database transaction with SELECT FOR UPDATE
-	
-	s.productService.mu.Lock()
-	defer s.productService.mu.Unlock()
-
-	// Check all products have enough stock
-	for productID, quantity := range productQuantities {
-		product, exists := s.productService.products[productID]
-		if !exists || product.Stock < quantity {
-			return false
-		}
+	tx := s.productService.BeginCheckoutTx(cartID, change)
+	tx.ShipTo(address)
+	if err := tx.ReserveMany(productQuantities); err != nil {
+		tx.Abort()
+		return nil, false
 	}
+	return tx, true
+}
 
-	// Reserve inventory
-	for productID, quantity := range productQuantities {
-		s.productService.products[productID].Stock -= quantity
+// saveOrder persists a new order and counts it in the stats
+func (s *OrderService) saveOrder(order *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(order.History) == 0 {
+		order.History = []models.OrderStatusChange{{
+			To: order.Status,
+			At: order.CreatedAt,
+		}}
+	}
+	if err := s.repo.Save(order); err != nil {
+		return err
 	}
 
-	return true
+	s.stats.Lock()
+	s.stats.totalOrders++
+	s.stats.Unlock()
+	return nil
 }
 
 // ============================================
 // FLASH SALE RACE CONDITION SCENARIO
 // ============================================
-func (s *OrderService) FlashSalePurchase(productID, quantity, userID int) (*models.Order, error) {
+func (s *OrderService) FlashSalePurchase(item models.SKURef, quantity, userID int) (*models.Order, error) {
+	if !s.userService.Exists(userID) {
+		return nil, ErrUserNotFound
+	}
+
 	// Simulate flash sale scenario where thousands try to buy same product
 	
 	// Step 1: Check product exists and is in flash sale
-	product, exists := s.productService.GetProductByID(productID)
-	if !exists {
+	product, exists := s.productService.GetProductByID(item.ProductID)
+	if !exists || product.IsDeleted() {
 		return nil, fmt.Errorf("product not found")
 	}
+	name, price, stock, ok := product.Unit(item.SKU)
+	if !ok {
+		return nil, skuError(product, item.SKU)
+	}
 
 	// Step 2: Check stock - RACE CONDITION HOTSPOT!
-	if product.Stock < quantity {
+	if stock < quantity {
 		return nil, fmt.Errorf("out of stock")
 	}
 
@@ -324,34 +462,213 @@ func (s *OrderService) FlashSalePurchase(productID, quantity, userID int) (*mode
 	time.Sleep(time.Millisecond * time.Duration(50+(userID%100)))
 
 	// Step 3: Update stock - ANOTHER RACE CONDITION!
-	success, err := s.productService.UpdateStock(productID, quantity)
+	orderID := fmt.Sprintf("flash_%d_%d", userID, time.Now().UnixNano())
+	success, allocations, err := s.productService.UpdateStock(item, quantity, models.StockChange{
+		Type:      models.MovementOrder,
+		Actor:     models.UserActor(userID),
+		Reason:    "flash sale",
+		Reference: orderID,
+	})
 	if !success || err != nil {
 		return nil, fmt.Errorf("purchase failed")
 	}
 
 	// Create order
-	orderID := fmt.Sprintf("flash_%d_%d", userID, time.Now().UnixNano())
 	order := &models.Order{
 		ID:     orderID,
 		UserID: userID,
 		Items: []models.OrderItem{{
-			ProductID: productID,
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
 			Quantity:  quantity,
-			Price:     product.Price,
-			Name:      product.Name,
+			Price:     price,
+			Name:      name,
+			// So cancelling puts the units back where they came from
+			Allocations: allocations,
 		}},
-		Total:     product.Price * float64(quantity),
+		Total:     price * float64(quantity),
 		Status:    models.OrderStatusPending,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
+	if err := s.saveOrder(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ============================================
+// ORDER RETRIEVAL
+// ============================================
+
+// GetOrder returns an order if it belongs to userID.
+func (s *OrderService) GetOrder(orderID string, userID int) (*models.Order, error) {
+	order, exists := s.GetOrderByID(orderID)
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// GetOrderByID returns any order, without an ownership check.
+func (s *OrderService) GetOrderByID(orderID string) (*models.Order, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo.FindByID(orderID)
+}
+
+// ListUserOrders returns one page of a user's orders, newest first, and
+// the cursor of the next page (nil on the last one).
+func (s *OrderService) ListUserOrders(userID int, filter models.OrderFilter, req models.PageRequest) ([]models.Order, int, *models.Cursor) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return paginateOrders(s.repo.FindByUserID(userID), filter, req)
+}
+
+// ListOrders returns one page of all orders, newest first, and the cursor
+// of the next page.
+func (s *OrderService) ListOrders(filter models.OrderFilter, req models.PageRequest) ([]models.Order, int, *models.Cursor) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return paginateOrders(s.repo.FindAll(), filter, req)
+}
+
+// UnitsSold counts the units ordered per SKU since the given time, not
+// counting cancelled orders.
+func (s *OrderService) UnitsSold(since time.Time) map[models.SKURef]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sold := make(map[models.SKURef]int)
+	for _, order := range s.repo.FindAll() {
+		if order.Status == models.OrderStatusCancelled || order.CreatedAt.Before(since) {
+			continue
+		}
+		for _, item := range order.Items {
+			sold[item.Ref()] += item.Quantity
+		}
+	}
+	return sold
+}
+
+// OrderCursorSort tags order cursors so a product cursor is not mistaken
+// for one.
+const OrderCursorSort = "orders"
+
+// paginateOrders filters orders and returns the requested page newest
+// first (ties by ID), plus the number of matches and the next cursor.
+func paginateOrders(orders []*models.Order, filter models.OrderFilter, req models.PageRequest) ([]models.Order, int, *models.Cursor) {
+	var matched []*models.Order
+	for _, order := range orders {
+		if filter.Matches(order) {
+			matched = append(matched, order)
+		}
+	}
+
+	var at **models.Order
+	if req.After != nil {
+		order := &models.Order{ID: req.After.ID, CreatedAt: req.After.Time}
+		at = &order
+	}
+	page, more := pageOf(matched, req, at, newestOrderFirst)
+
+	results := make([]models.Order, 0, len(page))
+	for _, order := range page {
+		results = append(results, *order)
+	}
+
+	var next *models.Cursor
+	if more {
+		last := page[len(page)-1]
+		next = &models.Cursor{Sort: OrderCursorSort, Time: last.CreatedAt, ID: last.ID}
+	}
+	return results, len(matched), next
+}
+
+func newestOrderFirst(a, b *models.Order) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// ============================================
+// ORDER LIFECYCLE
+// ============================================
+
+// TransitionOrder moves an order to the next status, enforcing the rules in
+// models.OrderStatus.CanTransitionTo. Cancelling returns the order's stock.
+// actor is who made the change (see models.UserActor).
+func (s *OrderService) TransitionOrder(orderID string, to models.OrderStatus, reason, actor string) (*models.Order, error) {
 	s.mu.Lock()
-	s.orders[orderID] = order
-	s.userOrders[userID] = append(s.userOrders[userID], orderID)
-	s.stats.totalOrders++
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+
+	order, exists := s.repo.FindByID(orderID)
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
 
+	return s.transitionLocked(order, to, reason, actor)
+}
+
+// CancelOrder cancels an order on behalf of its owner.
+func (s *OrderService) CancelOrder(orderID string, userID int, reason string) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.repo.FindByID(orderID)
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderForbidden
+	}
+
+	return s.transitionLocked(order, models.OrderStatusCancelled, reason, models.UserActor(userID))
+}
+
+// transitionLocked applies the status change. Caller holds s.mu.
+func (s *OrderService) transitionLocked(order *models.Order, to models.OrderStatus, reason, actor string) (*models.Order, error) {
+	from := order.Status
+	if !from.CanTransitionTo(to) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+	if to == models.OrderStatusShipped && !order.ReadyToShip(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotReady, order.ID)
+	}
+
+	if to == models.OrderStatusCancelled {
+		if err := s.productService.ReleaseStock(order.Items, models.StockChange{
+			Type:      models.MovementCancel,
+			Actor:     actor,
+			Reason:    reason,
+			Reference: order.ID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to return stock: %w", err)
+		}
+	}
+
+	now := time.Now()
+	order.Status = to
+	order.UpdatedAt = now
+	order.History = append(order.History, models.OrderStatusChange{
+		From:   from,
+		To:     to,
+		Reason: reason,
+		At:     now,
+	})
+
+	if err := s.repo.Save(order); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
 
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"go-ecommerce/internal/auth"
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
+)
+
+// newTestOrderService returns an order service on top of products with one
+// registered customer.
+func newTestOrderService(t *testing.T, products *ProductService) (*OrderService, *models.User) {
+	t.Helper()
+
+	users := NewUserService(repository.NewMemoryUserRepository(), auth.NewTokenManager([]byte("test-secret"), 0))
+	user, err := users.Register(models.RegisterRequest{Email: "buyer@example.com", Password: "secret-password", Name: "Buyer"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	carts := NewCartService(repository.NewMemoryCartRepository(), users)
+	return NewOrderService(repository.NewMemoryOrderRepository(), products, carts, users), user
+}
+
+func TestFlashSaleCancelReturnsLocatedStock(t *testing.T) {
+	products := newTestProductService(t)
+	product := createTestProduct(t, products, models.CreateProductRequest{Stock: 6})
+	item := models.SKURef{ProductID: product.ID}
+	placeStock(t, products, item, "JKT", 4)
+	placeStock(t, products, item, "SBY", 2)
+	orders, user := newTestOrderService(t, products)
+
+	order, err := orders.FlashSalePurchase(item, 5, user.ID)
+	if err != nil {
+		t.Fatalf("FlashSalePurchase: %v", err)
+	}
+	allocated := 0
+	for _, part := range order.Items[0].Allocations {
+		allocated += part.Quantity
+	}
+	if allocated != 5 {
+		t.Errorf("order line allocations = %v, want 5 units", order.Items[0].Allocations)
+	}
+
+	if _, err := orders.CancelOrder(order.ID, user.ID, "changed my mind"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	stored, _ := products.GetProductByID(product.ID)
+	if stored.Stock != 6 || stored.Locations["JKT"] != 4 || stored.Locations["SBY"] != 2 {
+		t.Errorf("after cancel: stock %d locations %v, want 6 with JKT 4 and SBY 2", stored.Stock, stored.Locations)
+	}
+}
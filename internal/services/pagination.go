@@ -0,0 +1,76 @@
+package services
+
+import (
+	"container/heap"
+	"sort"
+
+	"go-ecommerce/internal/models"
+)
+
+// pageOf returns the requested page of items in the order given by less and
+// whether more items follow it. In cursor mode the page starts after at,
+// the item the cursor was taken from, so inserts and deletes elsewhere in
+// the list don't shift it. items may be reordered.
+func pageOf[T any](items []T, req models.PageRequest, at *T, less func(a, b T) bool) ([]T, bool) {
+	start := (req.Page - 1) * req.Limit
+	if at != nil {
+		rest := items[:0]
+		for _, item := range items {
+			if less(*at, item) {
+				rest = append(rest, item)
+			}
+		}
+		items = rest
+		start = 0
+	}
+
+	end := start + req.Limit
+	if start >= len(items) {
+		return nil, false
+	}
+	if end >= len(items) {
+		return topK(items, len(items), less)[start:], false
+	}
+	return topK(items, end, less)[start:end], true
+}
+
+// topK returns the first k items in the order given by less, sorted. It
+// only fully sorts when k covers every item, so early pages of a large
+// result stay cheap.
+func topK[T any](items []T, k int, less func(a, b T) bool) []T {
+	if k >= len(items) {
+		sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+		return items
+	}
+	if k <= 0 {
+		return nil
+	}
+
+	h := &topKHeap[T]{less: less, items: make([]T, 0, k)}
+	for _, item := range items {
+		if h.Len() < k {
+			heap.Push(h, item)
+		} else if less(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+	sort.Slice(h.items, func(i, j int) bool { return less(h.items[i], h.items[j]) })
+	return h.items
+}
+
+// topKHeap keeps the worst of the best k items at the root.
+type topKHeap[T any] struct {
+	items []T
+	less  func(a, b T) bool
+}
+
+func (h *topKHeap[T]) Len() int           { return len(h.items) }
+func (h *topKHeap[T]) Less(i, j int) bool { return h.less(h.items[j], h.items[i]) }
+func (h *topKHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *topKHeap[T]) Push(x any)         { h.items = append(h.items, x.(T)) }
+func (h *topKHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -1,80 +1,168 @@
 package services
 
 import (
+	"fmt"
 	"sync"
 	"time"
 	
 	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
 )
 
 type ProductService struct {
-	mu       sync.RWMutex
-	products map[int]*models.Product
-	nextID   int
+	mu         sync.RWMutex
+	repo       repository.ProductRepository
+	categories *CategoryService
+	index      *searchIndex
+	holds      *stockHolds
+	monitor    *stockMonitor
+	warehouses *warehouseConfig
+	ledger     repository.LedgerRepository
+	restocked  chan struct{} // see Restocked
+	nextID     int
+
+	nextMovementID int64
 }
 
-func NewProductService() *ProductService {
-	// Initialize with some sample products
-	return &ProductService{
-		products: make(map[int]*models.Product),
-		nextID:   1,
+func NewProductService(repo repository.ProductRepository, categories *CategoryService, ledger repository.LedgerRepository) *ProductService {
+	s := &ProductService{
+		repo:       repo,
+		categories: categories,
+		ledger:     ledger,
+		index:      newSearchIndex(),
+		holds:      newStockHolds(),
+		monitor:    newStockMonitor(),
+		warehouses: newWarehouseConfig(),
+		restocked:  make(chan struct{}, 1),
+		nextID:     repo.MaxID() + 1,
+
+		nextMovementID: ledger.MaxID(),
 	}
+	s.index.addAll(s.activeProducts())
+	return s
 }
 
-func (s *ProductService) InitSampleData() {
+// InitSampleData seeds the catalog. It does nothing when the repository
+// already holds products, so a persistent store keeps its data across restarts.
+func (s *ProductService) InitSampleData() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.repo.Count() > 0 {
+		return nil
+	}
+
+	var categories []*models.Category
+	for _, name := range []string{"Electronics", "Clothing", "Books", "Home"} {
+		category, err := s.categories.Resolve(name)
+		if err != nil {
+			return err
+		}
+		categories = append(categories, category)
+	}
+
 	// Add 1000 sample products for load testing
 	for i := 1; i <= 1000; i++ {
-		s.products[i] = &models.Product{
+		product := &models.Product{
 			ID:          i,
 			Name:        "Product " + string(rune('A' + (i%26))),
 			Description: "Description for product " + string(rune('A' + (i%26))),
 			Price:       float64((i%1000) + 1),
 			Stock:       (i % 100) + 1,
-			Category:    []string{"Electronics", "Clothing", "Books", "Home"}[i%4],
+			Category:    categories[i%4].Name,
+			CategoryID:  categories[i%4].ID,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
+		if err := s.repo.Save(product); err != nil {
+			return err
+		}
+		s.index.add(product)
+		if err := s.recordLocked(product, nil, models.StockChange{Actor: models.ActorSystem, Reason: "sample data"}); err != nil {
+			return err
+		}
 	}
 	s.nextID = 1001
+	return nil
+}
+
+// LinkCategories gives products stored before categories existed the
+// category named by their Category path, creating it if needed. It returns
+// how many products changed.
+func (s *ProductService) LinkCategories() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	linked := 0
+	for _, product := range s.repo.FindAll() {
+		if product.CategoryID != 0 || product.Category == "" {
+			continue
+		}
+		category, err := s.categories.Resolve(product.Category)
+		if err != nil {
+			return linked, fmt.Errorf("product %d: %w", product.ID, err)
+		}
+		product.CategoryID = category.ID
+		product.Category = s.categories.Path(category.ID)
+		if err := s.repo.Save(product); err != nil {
+			return linked, err
+		}
+		if !product.IsDeleted() {
+			s.index.add(product)
+		}
+		linked++
+	}
+	return linked, nil
 }
 
 // Get all products (with pagination) - READ HEAVY
-func (s *ProductService) GetAllProducts(page, limit int) ([]models.Product, int) {
+// Ordered by ID unless another sort is given. The returned cursor points
+// at the next page and is nil on the last one.
+func (s *ProductService) GetAllProducts(req models.PageRequest, sortBy models.ProductSort) ([]models.Product, int, *models.Cursor) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	// Simulate database query delay
 	time.Sleep(time.Millisecond * 10)
 
-	total := len(s.products)
-	if total == 0 {
-		return []models.Product{}, 0
-	}
+	active := s.activeProducts()
+	total := len(active)
 
-	start := (page - 1) * limit
-	end := start + limit
+	var at **models.Product
+	if req.After != nil {
+		product := productAt(req.After)
+		at = &product
+	}
+	page, more := pageOf(active, req, at, productOrder(sortBy))
 
-	if start >= total {
-		return []models.Product{}, total
+	products := make([]models.Product, 0, len(page))
+	for _, product := range page {
+		products = append(products, s.withAvailability(product))
 	}
-	if end > total {
-		end = total
+
+	var next *models.Cursor
+	if more {
+		next = productCursor(sortBy, page[len(page)-1])
 	}
+	return products, total, next
+}
 
-	products := make([]models.Product, 0, end-start)
-	for i := start; i < end; i++ {
-		if product, exists := s.products[i+1]; exists {
-			products = append(products, *product)
+// activeProducts returns every product that is not soft-deleted, by ID.
+// Caller holds s.mu.
+func (s *ProductService) activeProducts() []*models.Product {
+	all := s.repo.FindAll()
+	active := make([]*models.Product, 0, len(all))
+	for _, product := range all {
+		if !product.IsDeleted() {
+			active = append(active, product)
 		}
 	}
-
-	return products, total
+	return active
 }
 
 // Get product by ID - READ HEAVY
+// Soft-deleted products are returned too (check IsDeleted) so order
+// history can still show them.
 func (s *ProductService) GetProductByID(id int) (*models.Product, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -82,54 +170,93 @@ func (s *ProductService) GetProductByID(id int) (*models.Product, bool) {
 	// Simulate database query delay
 	time.Sleep(time.Millisecond * 5)
 
-	product, exists := s.products[id]
-	if !exists {
-		return nil, false
-	}
+	return s.repo.FindByID(id)
+}
 
-	return product, true
+// GetProductWithAvailability returns a copy of the product with Available
+// filled in, for showing to shoppers. Soft-deleted products are not found.
+func (s *ProductService) GetProductWithAvailability(id int) (models.Product, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Simulate database query delay
+	time.Sleep(time.Millisecond * 5)
+
+	product, exists := s.repo.FindByID(id)
+	if !exists || product.IsDeleted() {
+		return models.Product{}, false
+	}
+	return s.withAvailability(product), true
 }
 
 // Search products - READ HEAVY with filtering
-func (s *ProductService) SearchProducts(query string, category string, minPrice, maxPrice float64, page, limit int) ([]models.Product, int) {
+// Results are ordered by search.Sort, relevance by default (ID order
+// without a query). Facets cover every match. Relevance cursors hold the
+// score, so catalog changes between pages can move items across them.
+func (s *ProductService) SearchProducts(search models.ProductSearch) ([]models.Product, int, *models.Cursor, models.SearchFacets) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Simulate complex search delay
-	time.Sleep(time.Millisecond * 20)
+	hits := s.index.search(search.Query, searchFilter{
+		minPrice: search.MinPrice,
+		maxPrice: search.MaxPrice,
+	})
+	hits, facets := s.facetHits(hits, search.Categories)
+	total := len(hits)
+
+	var products []models.Product
+	var next *models.Cursor
+	if less := hitOrder(search.Sort); less != nil {
+		var at *searchHit
+		if search.Page.After != nil {
+			hit := hitAt(search.Page.After)
+			at = &hit
+		}
+		page, more := pageOf(hits, search.Page, at, less)
 
-	var results []models.Product
-	for _, product := range s.products {
-		// Simple search logic
-		matchesQuery := query == "" || 
-			contains(product.Name, query) || 
-			contains(product.Description, query)
-		matchesCategory := category == "" || product.Category == category
-		matchesPrice := (minPrice == 0 || product.Price >= minPrice) &&
-			(maxPrice == 0 || product.Price <= maxPrice)
+		products = make([]models.Product, 0, len(page))
+		for _, hit := range page {
+			if product, exists := s.repo.FindByID(hit.productID); exists {
+				products = append(products, s.withAvailability(product))
+			}
+		}
+		if more {
+			next = hitCursor(search.Sort, page[len(page)-1])
+		}
+		return products, total, next, facets
+	}
 
-		if matchesQuery && matchesCategory && matchesPrice {
-			results = append(results, *product)
+	// Other sorts need the full products
+	matched := make([]*models.Product, 0, len(hits))
+	for _, hit := range hits {
+		if product, exists := s.repo.FindByID(hit.productID); exists {
+			matched = append(matched, product)
 		}
 	}
 
-	// Pagination
-	total := len(results)
-	start := (page - 1) * limit
-	end := start + limit
+	var at **models.Product
+	if search.Page.After != nil {
+		product := productAt(search.Page.After)
+		at = &product
+	}
+	page, more := pageOf(matched, search.Page, at, productOrder(search.Sort))
 
-	if start >= total {
-		return []models.Product{}, total
+	products = make([]models.Product, 0, len(page))
+	for _, product := range page {
+		products = append(products, s.withAvailability(product))
 	}
-	if end > total {
-		end = total
+	if more {
+		next = productCursor(search.Sort, page[len(page)-1])
 	}
-
-	return results[start:end], total
+	return products, total, next, facets
 }
 
 // Update stock - WRITE with potential RACE CONDITION
-func (s *ProductService) UpdateStock(productID, quantity int) (bool, error) {
+// item.SKU picks the variant; it must be empty for products without
+// variants. A SKU that does not fit the product is an error. allocations
+// are the locations the units came from, for the order line (see
+// ReleaseStock).
+func (s *ProductService) UpdateStock(item models.SKURef, quantity int, change models.StockChange) (success bool, allocations []models.LocationQuantity, err error) {
 	// VERSION 1: Tanpa lock - ini akan menyebabkan race condition!
 	// product, exists := s.products[productID]
 	// if !exists {
@@ -150,45 +277,604 @@ func (s *ProductService) UpdateStock(productID, quantity int) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	product, exists := s.products[productID]
-	if !exists {
-		return false, nil
+	product, exists := s.repo.FindByID(item.ProductID)
+	if !exists || product.IsDeleted() {
+		return false, nil, nil
+	}
+	_, _, stock, ok := product.Unit(item.SKU)
+	if !ok {
+		return false, nil, skuError(product, item.SKU)
 	}
 
 	// Simulate processing delay
 	time.Sleep(time.Millisecond * 15)
 
-	if product.Stock < quantity {
-		return false, nil
+	// Units held by carts are not for sale here
+	if s.availableLocked("", item, stock) < quantity {
+		return false, nil, nil
+	}
+
+	// No shipping address here, so locations go in the strategy's default order
+	ranked := s.rankLocationsLocked(nil, map[models.SKURef]int{item: quantity})
+	plan := planLocations(product, item.SKU, quantity, ranked)
+	if err := s.adjustLocatedLocked(item, -quantity, negated(plan), change); err != nil {
+		return false, nil, err
+	}
+	return true, plan, nil
+}
+
+// ============================================
+// CATALOG MANAGEMENT
+// ============================================
+func (s *ProductService) CreateProduct(req models.CreateProductRequest, actor string) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	variants, err := newVariants(req.Variants)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.categoryFor(req.CategoryID, req.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	product := &models.Product{
+		ID:          s.nextID,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Stock:       req.Stock,
+		Category:    s.categories.Path(category.ID),
+		CategoryID:  category.ID,
+		Variants:    variants,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.ReorderPoint != nil {
+		product.ReorderPoint = *req.ReorderPoint
+	}
+	if err := applyStockPolicy(product, req.StockPolicy, req.BackorderLimit, req.ReleaseDate); err != nil {
+		return nil, err
+	}
+	product.SyncVariants()
+	if err := s.repo.Save(product); err != nil {
+		return nil, err
+	}
+	s.index.add(product)
+	s.nextID++
+	if err := s.recordLocked(product, nil, models.StockChange{Actor: actor, Reason: "product created"}); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// UpdateProduct applies a partial update: zero-valued fields in req are
+// left unchanged, matching the omitempty validation rules. Products with
+// variants take price and stock changes per variant only.
+func (s *ProductService) UpdateProduct(id int, req models.UpdateProductRequest, actor string) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.repo.FindByID(id)
+	if !exists || product.IsDeleted() {
+		return nil, fmt.Errorf("%w: %d", ErrProductNotFound, id)
+	}
+	if product.HasVariants() && (req.Price != 0 || req.Stock != 0) {
+		return nil, fmt.Errorf("%w: set price and stock on the variant", ErrSKURequired)
+	}
+	policy, limit, releaseDate := product.StockPolicy, product.BackorderLimit, product.ReleaseDate
+	if req.StockPolicy != "" {
+		policy = req.StockPolicy
+	}
+	if req.BackorderLimit != nil {
+		limit = *req.BackorderLimit
+	}
+	if req.ReleaseDate != nil {
+		releaseDate = req.ReleaseDate
+	}
+	if err := checkStockPolicy(product.Name, policy, releaseDate); err != nil {
+		return nil, err
+	}
+	if req.Stock != 0 && product.Locations != nil {
+		return nil, fmt.Errorf("%w: product %d", ErrLocationRequired, id)
+	}
+	if req.CategoryID != 0 || req.Category != "" {
+		category, err := s.categoryFor(req.CategoryID, req.Category)
+		if err != nil {
+			return nil, err
+		}
+		product.CategoryID = category.ID
+		product.Category = s.categories.Path(category.ID)
 	}
 
-	product.Stock -= quantity
+	if req.Name != "" {
+		product.Name = req.Name
+	}
+	if req.Description != "" {
+		product.Description = req.Description
+	}
+	if req.Price != 0 {
+		product.Price = req.Price
+	}
+	if req.ReorderPoint != nil {
+		product.ReorderPoint = *req.ReorderPoint
+	}
+	if err := applyStockPolicy(product, policy, limit, releaseDate); err != nil {
+		return nil, err
+	}
+	before := stockLevels(product)
+	if req.Stock != 0 {
+		product.Stock = req.Stock
+	}
 	product.UpdatedAt = time.Now()
-	return true, nil
+
+	if err := s.repo.Save(product); err != nil {
+		return nil, err
+	}
+	s.index.add(product)
+	if err := s.recordLocked(product, before, models.StockChange{Type: models.MovementAdjustment, Actor: actor, Reason: "product updated"}); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// DeleteProduct hides a product from the catalog and blocks new purchases.
+// The record is kept so existing orders still resolve.
+func (s *ProductService) DeleteProduct(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.repo.FindByID(id)
+	if !exists || product.IsDeleted() {
+		return fmt.Errorf("%w: %d", ErrProductNotFound, id)
+	}
+
+	now := time.Now()
+	product.DeletedAt = &now
+	product.UpdatedAt = now
+	if err := s.repo.Save(product); err != nil {
+		return err
+	}
+	s.index.remove(id)
+	return nil
+}
+
+// ImportRowError is an import row ImportProducts rejected, by its index in
+// the items it was given.
+type ImportRowError struct {
+	Index int
+	Err   error
+}
+
+// ImportErrors lists every rejected row of an import.
+type ImportErrors []ImportRowError
+
+func (e ImportErrors) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("import row %d: %v", e[0].Index+1, e[0].Err)
+	}
+	return fmt.Sprintf("%d import rows rejected, first: row %d: %v", len(e), e[0].Index+1, e[0].Err)
+}
+
+func (e ImportErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, rowErr := range e {
+		errs[i] = rowErr.Err
+	}
+	return errs
+}
+
+// ImportProducts creates or replaces products in one batch. Items with ID 0
+// get a new ID; any other ID is created or overwritten (restoring it if it
+// was deleted), except that a row without a variants list keeps the
+// product's variants. Every row is checked before anything is written: if
+// any fails, nothing is imported and err is an ImportErrors. With dryRun
+// set nothing is written, only counted, and the counts cover the rows that
+// passed.
+func (s *ProductService) ImportProducts(items []models.ProductImport, dryRun bool, actor string) (created, updated int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every row before writing any
+	var rejected ImportErrors
+	variants := make([][]models.Variant, len(items))
+	for i, item := range items {
+		if variants[i], err = s.checkImportLocked(item); err != nil {
+			rejected = append(rejected, ImportRowError{Index: i, Err: err})
+			continue
+		}
+		if _, exists := s.repo.FindByID(item.ID); item.ID != 0 && exists {
+			updated++
+		} else {
+			created++
+		}
+	}
+	if len(rejected) > 0 {
+		if dryRun {
+			return created, updated, rejected
+		}
+		return 0, 0, rejected
+	}
+	if dryRun {
+		return created, updated, nil
+	}
+	created, updated = 0, 0
+
+	// Create missing categories before the first product is written, so a
+	// failure here leaves the products alone. Not in the check above: a
+	// dry run or a rejected import must not create categories.
+	categories := make([]*models.Category, len(items))
+	for i, item := range items {
+		if categories[i], err = s.categoryFor(item.CategoryID, item.Category); err != nil {
+			return 0, 0, ImportErrors{{Index: i, Err: err}}
+		}
+	}
+
+	change := models.StockChange{Type: models.MovementAdjustment, Actor: actor, Reason: "catalog import"}
+
+	now := time.Now()
+	for i, item := range items {
+		category := categories[i]
+		existing, exists := s.repo.FindByID(item.ID)
+		if item.ID != 0 && exists {
+			updated++
+			before := stockLevels(existing)
+			existing.Name = item.Name
+			existing.Description = item.Description
+			existing.Price = item.Price
+			existing.Stock = item.Stock
+			existing.Category = s.categories.Path(category.ID)
+			existing.CategoryID = category.ID
+			// Rows without variants (CSV has no variant columns) keep the
+			// product's SKUs; an explicit empty list removes them
+			if item.Variants != nil {
+				existing.Variants = variants[i]
+			}
+			if item.ReorderPoint != nil {
+				existing.ReorderPoint = *item.ReorderPoint
+			}
+			// Rows that leave the policy out keep the current one
+			if item.StockPolicy != "" {
+				applyStockPolicy(existing, item.StockPolicy, item.BackorderLimit, item.ReleaseDate)
+			}
+			existing.SyncVariants()
+			existing.DeletedAt = nil
+			existing.UpdatedAt = now
+			if err := s.repo.Save(existing); err != nil {
+				return created, updated, err
+			}
+			s.index.add(existing)
+			if err := s.recordLocked(existing, before, change); err != nil {
+				return created, updated, err
+			}
+			continue
+		}
+
+		created++
+		id := item.ID
+		if id == 0 {
+			id = s.nextID
+		}
+		product := &models.Product{
+			ID:          id,
+			Name:        item.Name,
+			Description: item.Description,
+			Price:       item.Price,
+			Stock:       item.Stock,
+			Category:    s.categories.Path(category.ID),
+			CategoryID:  category.ID,
+			Variants:    variants[i],
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if item.ReorderPoint != nil {
+			product.ReorderPoint = *item.ReorderPoint
+		}
+		applyStockPolicy(product, item.StockPolicy, item.BackorderLimit, item.ReleaseDate)
+		product.SyncVariants()
+		if err := s.repo.Save(product); err != nil {
+			return created, updated, err
+		}
+		s.index.add(product)
+		if err := s.recordLocked(product, nil, change); err != nil {
+			return created, updated, err
+		}
+		if id >= s.nextID {
+			s.nextID = id + 1
+		}
+	}
+	return created, updated, nil
+}
+
+// checkImportLocked finds what would stop item from being imported,
+// without writing anything, and returns its variants. Caller holds s.mu.
+func (s *ProductService) checkImportLocked(item models.ProductImport) ([]models.Variant, error) {
+	variants, err := newVariants(item.Variants)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStockPolicy(item.Name, item.StockPolicy, item.ReleaseDate); err != nil {
+		return nil, err
+	}
+	if item.CategoryID != 0 {
+		if _, exists := s.categories.Get(item.CategoryID); !exists {
+			return nil, fmt.Errorf("%w: %d", ErrCategoryNotFound, item.CategoryID)
+		}
+	} else if len(splitCategoryPath(item.Category)) == 0 {
+		return nil, fmt.Errorf("%w: empty path", ErrCategoryNotFound)
+	}
+
+	existing, exists := s.repo.FindByID(item.ID)
+	if item.ID == 0 || !exists {
+		return variants, nil
+	}
+	// Stock tracked per location only changes per location, so a row may
+	// repeat it but not replace it; the locations are kept
+	if existing.Locations != nil && (len(variants) > 0 || item.Stock != existing.Stock) {
+		return nil, fmt.Errorf("%w: product %d", ErrLocationRequired, item.ID)
+	}
+	for i := range variants {
+		old, found := existing.Variant(variants[i].SKU)
+		if !found || old.Locations == nil {
+			continue
+		}
+		if variants[i].Stock != old.Stock {
+			return nil, fmt.Errorf("%w: product %d sku %q", ErrLocationRequired, item.ID, old.SKU)
+		}
+		variants[i].Locations = old.Locations
+	}
+	return variants, nil
 }
 
-// Helper function for search
-func contains(s, substr string) bool {
-	// Simple case-insensitive contains
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+// ExportProducts returns a snapshot of the catalog ordered by ID.
+func (s *ProductService) ExportProducts(includeDeleted bool) []models.Product {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := s.repo.FindAll()
+	products := make([]models.Product, 0, len(all))
+	for _, product := range all {
+		if includeDeleted || !product.IsDeleted() {
+			products = append(products, *product)
+		}
+	}
+	return products
+}
+
+// Count returns the number of stored products, including deleted ones.
+func (s *ProductService) Count() int {
+	return s.repo.Count()
+}
+
+// ReleaseStock puts an order's units back into stock, e.g. when it is
+// cancelled; allocated units go back to their locations. Products or
+// variants that no longer exist are skipped.
+func (s *ProductService) ReleaseStock(items []models.OrderItem, change models.StockChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		product, exists := s.repo.FindByID(item.ProductID)
+		if !exists {
+			continue
+		}
+		if _, _, _, ok := product.Unit(item.SKU); !ok {
+			continue
+		}
+		if err := s.adjustLocatedLocked(item.Ref(), item.Quantity, item.Allocations, change); err != nil {
+			return err
 		}
 	}
-	return false
+	return nil
 }
 
 // Tambahkan method ini di ProductService
-func (s *ProductService) UpdateStockDirect(productID, newStock int) bool {
+// UpdateStockDirect sets a SKU's stock and returns what it was before. With
+// expected set it only writes if the stock is still *expected.
+func (s *ProductService) UpdateStockDirect(item models.SKURef, newStock int, expected *int, change models.StockChange) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	product, exists := s.products[productID]
+	product, exists := s.repo.FindByID(item.ProductID)
+	if !exists {
+		return 0, fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+	}
+	_, _, stock, ok := product.Unit(item.SKU)
+	if !ok {
+		return 0, skuError(product, item.SKU)
+	}
+	if product.UnitLocations(item.SKU) != nil {
+		return stock, fmt.Errorf("%w: product %d", ErrLocationRequired, item.ProductID)
+	}
+	if expected != nil && *expected != stock {
+		return stock, fmt.Errorf("%w: expected %d, is %d", ErrStockMismatch, *expected, stock)
+	}
+	if newStock == stock {
+		return stock, nil
+	}
+
+	product.AddStock(item.SKU, newStock-stock)
+	product.UpdatedAt = time.Now()
+	if err := s.repo.Save(product); err != nil {
+		return stock, err
+	}
+	return stock, s.appendMovementLocked(item, newStock-stock, newStock, change)
+}
+
+// ============================================
+// CATEGORIES
+// ============================================
+
+// categoryFor returns the category with id or, when id is 0, the one at
+// path, creating missing levels.
+func (s *ProductService) categoryFor(id int, path string) (*models.Category, error) {
+	if id == 0 {
+		return s.categories.Resolve(path)
+	}
+	category, exists := s.categories.Get(id)
 	if !exists {
-		return false
+		return nil, fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+	}
+	return category, nil
+}
+
+// UpdateCategory renames or moves a category and refreshes the category
+// path of every product in it or below it.
+func (s *ProductService) UpdateCategory(id int, req models.UpdateCategoryRequest) (*models.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	category, err := s.categories.Update(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := make(map[int]bool)
+	for _, descendant := range s.categories.Descendants(id) {
+		affected[descendant] = true
+	}
+	for _, product := range s.repo.FindAll() {
+		if !affected[product.CategoryID] {
+			continue
+		}
+		product.Category = s.categories.Path(product.CategoryID)
+		if err := s.repo.Save(product); err != nil {
+			return nil, err
+		}
+		if !product.IsDeleted() {
+			s.index.add(product)
+		}
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category that has no subcategories and no
+// products. Deleted products keep their category path as it was.
+func (s *ProductService) DeleteCategory(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, product := range s.repo.FindAll() {
+		if product.CategoryID == id && !product.IsDeleted() {
+			return fmt.Errorf("%w: %d", ErrCategoryInUse, id)
+		}
+	}
+	return s.categories.Delete(id)
+}
+
+// ============================================
+// VARIANTS
+// ============================================
+
+// UpsertVariant adds the variant sku to a product or replaces it. Adding
+// the first variant turns a plain product into one sold per variant; its
+// own price and stock are then derived from the variants. A product that
+// tracks its stock per location cannot take variants, and a variant that
+// does keeps its locations and its stock.
+func (s *ProductService) UpsertVariant(productID int, sku string, req models.UpsertVariantRequest, actor string) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.repo.FindByID(productID)
+	if !exists || product.IsDeleted() {
+		return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
 	}
 
-	product.Stock = newStock
+	before := stockLevels(product)
+	variant := models.Variant{
+		SKU:     sku,
+		Options: req.Options,
+		Price:   req.Price,
+		Stock:   req.Stock,
+	}
+	if existing, exists := product.Variant(sku); exists {
+		// Stock tracked per location only changes per location
+		if existing.Locations != nil {
+			if req.Stock != existing.Stock {
+				return nil, fmt.Errorf("%w: product %d sku %q", ErrLocationRequired, productID, sku)
+			}
+			variant.Locations = existing.Locations
+		}
+		*existing = variant
+	} else {
+		// The product's own locations would have no SKU to belong to
+		if !product.HasVariants() && product.Locations != nil {
+			return nil, fmt.Errorf("%w: product %d tracks stock per location, it cannot take variants", ErrLocationRequired, productID)
+		}
+		product.Variants = append(product.Variants, variant)
+	}
+	product.SyncVariants()
 	product.UpdatedAt = time.Now()
-	return true
+
+	if err := s.repo.Save(product); err != nil {
+		return nil, err
+	}
+	s.index.add(product)
+	if err := s.recordLocked(product, before, models.StockChange{Type: models.MovementAdjustment, Actor: actor, Reason: "variant updated"}); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// DeleteVariant removes a variant. Carts holding it can no longer be
+// ordered. A product left without variants keeps its last price and has
+// no stock until it is set again.
+func (s *ProductService) DeleteVariant(productID int, sku string, actor string) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.repo.FindByID(productID)
+	if !exists || product.IsDeleted() {
+		return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+
+	before := stockLevels(product)
+	for i := range product.Variants {
+		if product.Variants[i].SKU == sku {
+			product.Variants = append(product.Variants[:i], product.Variants[i+1:]...)
+			if product.HasVariants() {
+				product.SyncVariants()
+			} else {
+				product.Variants = nil
+				product.Stock = 0
+			}
+			product.UpdatedAt = time.Now()
+
+			if err := s.repo.Save(product); err != nil {
+				return nil, err
+			}
+			s.index.add(product)
+			if err := s.recordLocked(product, before, models.StockChange{Type: models.MovementAdjustment, Actor: actor, Reason: "variant deleted"}); err != nil {
+				return nil, err
+			}
+			return product, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q for product %d", ErrSKUNotFound, sku, productID)
+}
+
+// newVariants builds variants from a request, rejecting repeated SKUs.
+func newVariants(reqs []models.VariantRequest) ([]models.Variant, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	variants := make([]models.Variant, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for _, req := range reqs {
+		if seen[req.SKU] {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateSKU, req.SKU)
+		}
+		seen[req.SKU] = true
+		variants = append(variants, models.Variant{
+			SKU:     req.SKU,
+			Options: req.Options,
+			Price:   req.Price,
+			Stock:   req.Stock,
+		})
+	}
+	return variants, nil
 }
\ No newline at end of file
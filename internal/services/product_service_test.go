@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
+)
+
+// newTestProductService returns a product service on memory storage with
+// two warehouses, JKT and SBY.
+func newTestProductService(t *testing.T) *ProductService {
+	t.Helper()
+
+	categories := NewCategoryService(repository.NewMemoryCategoryRepository())
+	products := NewProductService(repository.NewMemoryProductRepository(), categories, repository.NewMemoryLedgerRepository())
+	warehouses := []models.Warehouse{{Code: "JKT"}, {Code: "SBY", Priority: 1}}
+	if err := products.SetWarehouses(warehouses, models.AllocateSingleLocation); err != nil {
+		t.Fatalf("SetWarehouses: %v", err)
+	}
+	return products
+}
+
+// createTestProduct creates a product from req, filling in the fields
+// every test needs.
+func createTestProduct(t *testing.T, products *ProductService, req models.CreateProductRequest) *models.Product {
+	t.Helper()
+
+	req.Name = "Test product"
+	req.Description = "A product for tests"
+	req.Category = "Testing"
+	if req.Price == 0 && len(req.Variants) == 0 {
+		req.Price = 10
+	}
+	product, err := products.CreateProduct(req, models.ActorSystem)
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	return product
+}
+
+// placeStock puts stock of item at a warehouse.
+func placeStock(t *testing.T, products *ProductService, item models.SKURef, location string, stock int) {
+	t.Helper()
+
+	if _, err := products.SetLocationStock(item, location, stock, nil, models.StockChange{Actor: models.ActorSystem}); err != nil {
+		t.Fatalf("SetLocationStock: %v", err)
+	}
+}
+
+func TestUpsertVariantKeepsLocations(t *testing.T) {
+	products := newTestProductService(t)
+	product := createTestProduct(t, products, models.CreateProductRequest{
+		Variants: []models.VariantRequest{{SKU: "TS-M", Price: 10, Stock: 5}},
+	})
+	item := models.SKURef{ProductID: product.ID, SKU: "TS-M"}
+	placeStock(t, products, item, "JKT", 5)
+
+	req := models.UpsertVariantRequest{Options: map[string]string{"size": "M"}, Price: 12, Stock: 5}
+	updated, err := products.UpsertVariant(product.ID, "TS-M", req, models.ActorSystem)
+	if err != nil {
+		t.Fatalf("UpsertVariant with the same stock: %v", err)
+	}
+	variant, _ := updated.Variant("TS-M")
+	if variant.Price != 12 || variant.Stock != 5 || variant.Locations["JKT"] != 5 {
+		t.Errorf("variant = price %v stock %d locations %v, want price 12 stock 5 at JKT", variant.Price, variant.Stock, variant.Locations)
+	}
+}
+
+func TestUpsertVariantRejectsStockChangeOnLocatedVariant(t *testing.T) {
+	products := newTestProductService(t)
+	product := createTestProduct(t, products, models.CreateProductRequest{
+		Variants: []models.VariantRequest{{SKU: "TS-M", Price: 10, Stock: 5}},
+	})
+	item := models.SKURef{ProductID: product.ID, SKU: "TS-M"}
+	placeStock(t, products, item, "JKT", 5)
+
+	req := models.UpsertVariantRequest{Price: 10, Stock: 8}
+	if _, err := products.UpsertVariant(product.ID, "TS-M", req, models.ActorSystem); !errors.Is(err, ErrLocationRequired) {
+		t.Fatalf("UpsertVariant error = %v, want ErrLocationRequired", err)
+	}
+	stored, _ := products.GetProductByID(product.ID)
+	variant, _ := stored.Variant("TS-M")
+	if variant.Stock != 5 || variant.Locations["JKT"] != 5 {
+		t.Errorf("variant = stock %d locations %v, want stock 5 at JKT", variant.Stock, variant.Locations)
+	}
+}
+
+func TestUpsertVariantRejectsFirstVariantOnLocatedProduct(t *testing.T) {
+	products := newTestProductService(t)
+	product := createTestProduct(t, products, models.CreateProductRequest{Stock: 4})
+	placeStock(t, products, models.SKURef{ProductID: product.ID}, "JKT", 4)
+
+	req := models.UpsertVariantRequest{Price: 10, Stock: 4}
+	if _, err := products.UpsertVariant(product.ID, "TS-M", req, models.ActorSystem); !errors.Is(err, ErrLocationRequired) {
+		t.Fatalf("UpsertVariant error = %v, want ErrLocationRequired", err)
+	}
+	stored, _ := products.GetProductByID(product.ID)
+	if stored.HasVariants() || stored.Stock != 4 || stored.Locations["JKT"] != 4 {
+		t.Errorf("product = variants %v stock %d locations %v, want no variants and stock 4 at JKT", stored.Variants, stored.Stock, stored.Locations)
+	}
+}
+
+func TestImportProductsWritesNothingWhenARowFails(t *testing.T) {
+	products := newTestProductService(t)
+	items := []models.ProductImport{
+		{CreateProductRequest: models.CreateProductRequest{Name: "Spade", Description: "Digs holes", Price: 20, Stock: 3, Category: "Garden > Tools"}},
+		{CreateProductRequest: models.CreateProductRequest{Name: "Seeds", Description: "Not out yet", Price: 5, Category: "Garden", StockPolicy: models.PolicyPreorder}},
+	}
+
+	_, _, err := products.ImportProducts(items, false, models.ActorSystem)
+	var rejected ImportErrors
+	if !errors.As(err, &rejected) || len(rejected) != 1 || rejected[0].Index != 1 {
+		t.Fatalf("ImportProducts error = %v, want row 2 rejected", err)
+	}
+	if count := products.Count(); count != 0 {
+		t.Errorf("%d products written, want none", count)
+	}
+	if _, exists := products.categories.Lookup("Garden"); exists {
+		t.Error("category Garden was created by a rejected import")
+	}
+
+	items[1].ReleaseDate = &time.Time{}
+	created, _, err := products.ImportProducts(items, false, models.ActorSystem)
+	if err != nil || created != 2 {
+		t.Fatalf("ImportProducts = %d created, %v; want 2 created", created, err)
+	}
+	if _, exists := products.categories.Lookup("Garden > Tools"); !exists {
+		t.Error("category Garden > Tools was not created")
+	}
+}
+
+func TestGetProductWithAvailabilityHidesDeletedProducts(t *testing.T) {
+	products := newTestProductService(t)
+	product := createTestProduct(t, products, models.CreateProductRequest{Stock: 4})
+	if err := products.DeleteProduct(product.ID); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+
+	if _, exists := products.GetProductWithAvailability(product.ID); exists {
+		t.Error("GetProductWithAvailability found a deleted product")
+	}
+	// Order history still resolves it
+	if _, exists := products.GetProductByID(product.ID); !exists {
+		t.Error("GetProductByID lost a deleted product")
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+
+	"go-ecommerce/internal/models"
+)
+
+// hitOrder returns the ordering for sorts that only need what the search
+// index carries, or nil when full products are needed.
+func hitOrder(by models.ProductSort) func(a, b searchHit) bool {
+	switch by {
+	case "", models.SortRelevance:
+		return func(a, b searchHit) bool {
+			if a.score != b.score {
+				return a.score > b.score
+			}
+			return a.productID < b.productID
+		}
+	case models.SortPriceAsc:
+		return func(a, b searchHit) bool {
+			if a.price != b.price {
+				return a.price < b.price
+			}
+			return a.productID < b.productID
+		}
+	case models.SortPriceDesc:
+		return func(a, b searchHit) bool {
+			if a.price != b.price {
+				return a.price > b.price
+			}
+			return a.productID < b.productID
+		}
+	}
+	return nil
+}
+
+// productOrder returns the ordering for by. Ties, and the default and
+// relevance sorts (there is no score outside search), go by ID.
+func productOrder(by models.ProductSort) func(a, b *models.Product) bool {
+	switch by {
+	case models.SortPriceAsc:
+		return func(a, b *models.Product) bool {
+			if a.Price != b.Price {
+				return a.Price < b.Price
+			}
+			return a.ID < b.ID
+		}
+	case models.SortPriceDesc:
+		return func(a, b *models.Product) bool {
+			if a.Price != b.Price {
+				return a.Price > b.Price
+			}
+			return a.ID < b.ID
+		}
+	case models.SortNewest:
+		return func(a, b *models.Product) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		}
+	case models.SortName:
+		return func(a, b *models.Product) bool {
+			if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+				return c < 0
+			}
+			return a.ID < b.ID
+		}
+	case models.SortStock:
+		return func(a, b *models.Product) bool {
+			if a.Stock != b.Stock {
+				return a.Stock > b.Stock
+			}
+			return a.ID < b.ID
+		}
+	}
+	return func(a, b *models.Product) bool {
+		return a.ID < b.ID
+	}
+}
+
+// hitCursor returns the cursor for a page ending at hit.
+func hitCursor(by models.ProductSort, hit searchHit) *models.Cursor {
+	cursor := &models.Cursor{Sort: string(by), ID: strconv.Itoa(hit.productID)}
+	switch by {
+	case "", models.SortRelevance:
+		cursor.Num = hit.score
+	case models.SortPriceAsc, models.SortPriceDesc:
+		cursor.Num = hit.price
+	}
+	return cursor
+}
+
+// hitAt is the inverse of hitCursor: the hit a page ended at.
+func hitAt(cursor *models.Cursor) searchHit {
+	id, _ := strconv.Atoi(cursor.ID)
+	return searchHit{productID: id, score: cursor.Num, price: cursor.Num}
+}
+
+// productCursor returns the cursor for a page ending at product.
+func productCursor(by models.ProductSort, product *models.Product) *models.Cursor {
+	cursor := &models.Cursor{Sort: string(by), ID: strconv.Itoa(product.ID)}
+	switch by {
+	case models.SortPriceAsc, models.SortPriceDesc:
+		cursor.Num = product.Price
+	case models.SortNewest:
+		cursor.Time = product.CreatedAt
+	case models.SortName:
+		cursor.Text = product.Name
+	case models.SortStock:
+		cursor.Num = float64(product.Stock)
+	}
+	return cursor
+}
+
+// productAt is the inverse of productCursor: enough of the product a page
+// ended at to compare with productOrder.
+func productAt(cursor *models.Cursor) *models.Product {
+	id, _ := strconv.Atoi(cursor.ID)
+	return &models.Product{
+		ID:        id,
+		Price:     cursor.Num,
+		CreatedAt: cursor.Time,
+		Name:      cursor.Text,
+		Stock:     int(cursor.Num),
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"sort"
+
+	"go-ecommerce/internal/models"
+)
+
+// priceBucketEdges are the lower bounds of the price facet buckets; the
+// last bucket is open-ended.
+var priceBucketEdges = []float64{0, 50, 100, 250, 500, 1000}
+
+// facetHits drops hits outside the selected categories (and their
+// descendants) and counts facets. Category counts are taken before the
+// category filter so every category stays selectable, and a hit counts
+// towards its category and every ancestor; the other facets describe the
+// filtered hits. The caller must hold s.mu.
+func (s *ProductService) facetHits(hits []searchHit, categories []int) ([]searchHit, models.SearchFacets) {
+	selected := make(map[int]bool, len(categories))
+	included := make(map[int]bool)
+	for _, id := range categories {
+		selected[id] = true
+		for _, descendant := range s.categories.Descendants(id) {
+			included[descendant] = true
+		}
+	}
+
+	facets := models.SearchFacets{
+		PriceRanges: make([]models.PriceBucket, len(priceBucketEdges)),
+	}
+	for i, min := range priceBucketEdges {
+		facets.PriceRanges[i].Min = min
+		if i+1 < len(priceBucketEdges) {
+			facets.PriceRanges[i].Max = priceBucketEdges[i+1]
+		}
+	}
+
+	categoryCounts := make(map[int]int)
+	filtered := hits[:0]
+	for _, hit := range hits {
+		categoryCounts[hit.categoryID]++
+		if len(included) > 0 && !included[hit.categoryID] {
+			continue
+		}
+		filtered = append(filtered, hit)
+
+		facets.PriceRanges[priceBucket(hit.price)].Count++
+
+		// In stock means something is left that carts do not hold, as the
+		// product's available stock shows
+		if product, exists := s.repo.FindByID(hit.productID); exists && s.totalAvailableLocked(product) > 0 {
+			facets.Availability.InStock++
+		} else {
+			facets.Availability.OutOfStock++
+		}
+	}
+
+	rolledUp := make(map[int]int, len(categoryCounts))
+	for id, count := range categoryCounts {
+		for _, ancestor := range s.categories.Ancestors(id) {
+			rolledUp[ancestor.ID] += count
+		}
+	}
+	categoryCounts = rolledUp
+
+	// Selected categories are listed even when nothing matches them
+	for id := range selected {
+		if _, exists := categoryCounts[id]; !exists {
+			categoryCounts[id] = 0
+		}
+	}
+	facets.Categories = make([]models.CategoryFacet, 0, len(categoryCounts))
+	for id, count := range categoryCounts {
+		facet := models.CategoryFacet{
+			ID:       id,
+			Category: s.categories.Path(id),
+			Count:    count,
+			Selected: selected[id],
+		}
+		if category, exists := s.categories.Get(id); exists {
+			facet.Slug = category.Slug
+		}
+		facets.Categories = append(facets.Categories, facet)
+	}
+	sort.Slice(facets.Categories, func(i, j int) bool {
+		a, b := facets.Categories[i], facets.Categories[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Category < b.Category
+	})
+
+	return filtered, facets
+}
+
+func priceBucket(price float64) int {
+	bucket := len(priceBucketEdges) - 1
+	for bucket > 0 && price < priceBucketEdges[bucket] {
+		bucket--
+	}
+	return bucket
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"testing"
+
+	"go-ecommerce/internal/models"
+)
+
+func TestAvailabilityFacetCountsHeldStockAsTaken(t *testing.T) {
+	products := newTestProductService(t)
+	held := createTestProduct(t, products, models.CreateProductRequest{Stock: 2})
+	createTestProduct(t, products, models.CreateProductRequest{Stock: 3})
+	if _, err := products.HoldStock("cart-1", models.SKURef{ProductID: held.ID}, 2); err != nil {
+		t.Fatalf("HoldStock: %v", err)
+	}
+
+	_, _, _, facets := products.SearchProducts(models.ProductSearch{Page: models.PageRequest{Page: 1, Limit: 10}})
+	if facets.Availability.InStock != 1 || facets.Availability.OutOfStock != 1 {
+		t.Errorf("availability facet = %+v, want 1 in stock and 1 out of stock", facets.Availability)
+	}
+}
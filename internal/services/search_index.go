@@ -0,0 +1,315 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go-ecommerce/internal/models"
+)
+
+// Field weights: a hit in the name counts more than one in the description.
+const (
+	nameWeight        = 3.0
+	categoryWeight    = 1.5
+	descriptionWeight = 1.0
+
+	// A prefix hit ("head" -> "headphones") scores at most this fraction of
+	// an exact hit, scaled by how much of the term the prefix covers.
+	prefixPenalty = 0.5
+)
+
+// searchIndex is an in-process inverted index over product text. It is
+// kept up to date by ProductService on every catalog write and holds only
+// products that are not deleted.
+//
+// Each product occupies a dense slot so scoring can use flat arrays
+// instead of maps; that is what keeps queries fast on large catalogs.
+type searchIndex struct {
+	mu       sync.RWMutex
+	postings map[string][]posting // term -> products containing it
+	terms    []string             // sorted, for prefix lookups
+	docs     []indexedDoc         // slot -> product; productID 0 marks a free slot
+	slots    map[int]int32        // product_id -> slot
+	free     []int32
+
+	scratch sync.Pool // *searchScratch
+}
+
+type posting struct {
+	slot   int32
+	weight float32
+}
+
+// indexedDoc keeps the fields search filters on, so filtering needs no
+// repository lookups.
+type indexedDoc struct {
+	productID  int
+	categoryID int
+	price      float64
+	terms      []string
+}
+
+// searchHit is a matching product and its relevance score. Category and
+// price are carried along for faceting.
+type searchHit struct {
+	productID  int
+	score      float64
+	categoryID int
+	price      float64
+}
+
+// searchFilter narrows matches. Zero values match everything. Categories
+// are filtered later, while faceting.
+type searchFilter struct {
+	minPrice float64
+	maxPrice float64
+}
+
+func (f searchFilter) matches(doc *indexedDoc) bool {
+	return (f.minPrice == 0 || doc.price >= f.minPrice) &&
+		(f.maxPrice == 0 || doc.price <= f.maxPrice)
+}
+
+func (doc *indexedDoc) hit(score float64) searchHit {
+	return searchHit{
+		productID:  doc.productID,
+		score:      score,
+		categoryID: doc.categoryID,
+		price:      doc.price,
+	}
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string][]posting),
+		slots:    make(map[int]int32),
+	}
+}
+
+// add indexes a product, replacing any previous version of it.
+func (idx *searchIndex) add(product *models.Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, term := range idx.addLocked(product) {
+		idx.insertTermLocked(term)
+	}
+}
+
+// addAll indexes many products, sorting the term list once at the end.
+func (idx *searchIndex) addAll(products []*models.Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var newTerms []string
+	for _, product := range products {
+		newTerms = append(newTerms, idx.addLocked(product)...)
+	}
+	if len(newTerms) > 0 {
+		idx.terms = append(idx.terms, newTerms...)
+		sort.Strings(idx.terms)
+	}
+}
+
+// addLocked indexes the product and returns terms not seen before; the
+// caller must add them to idx.terms.
+func (idx *searchIndex) addLocked(product *models.Product) []string {
+	idx.removeLocked(product.ID)
+
+	weights := make(map[string]float64)
+	for _, term := range tokenize(product.Name) {
+		weights[term] += nameWeight
+	}
+	for _, term := range tokenize(product.Category) {
+		weights[term] += categoryWeight
+	}
+	for _, term := range tokenize(product.Description) {
+		weights[term] += descriptionWeight
+	}
+
+	var slot int32
+	if n := len(idx.free); n > 0 {
+		slot = idx.free[n-1]
+		idx.free = idx.free[:n-1]
+	} else {
+		slot = int32(len(idx.docs))
+		idx.docs = append(idx.docs, indexedDoc{})
+	}
+
+	var newTerms []string
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if _, exists := idx.postings[term]; !exists {
+			newTerms = append(newTerms, term)
+		}
+		idx.postings[term] = append(idx.postings[term], posting{slot: slot, weight: float32(weight)})
+		terms = append(terms, term)
+	}
+
+	idx.docs[slot] = indexedDoc{
+		productID:  product.ID,
+		categoryID: product.CategoryID,
+		price:      product.Price,
+		terms:      terms,
+	}
+	idx.slots[product.ID] = slot
+	return newTerms
+}
+
+func (idx *searchIndex) remove(productID int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(productID)
+}
+
+func (idx *searchIndex) removeLocked(productID int) {
+	slot, exists := idx.slots[productID]
+	if !exists {
+		return
+	}
+
+	for _, term := range idx.docs[slot].terms {
+		postings := idx.postings[term]
+		for i := range postings {
+			if postings[i].slot == slot {
+				postings[i] = postings[len(postings)-1]
+				postings = postings[:len(postings)-1]
+				break
+			}
+		}
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+			idx.deleteTermLocked(term)
+		} else {
+			idx.postings[term] = postings
+		}
+	}
+
+	idx.docs[slot] = indexedDoc{}
+	delete(idx.slots, productID)
+	idx.free = append(idx.free, slot)
+}
+
+func (idx *searchIndex) insertTermLocked(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = term
+}
+
+func (idx *searchIndex) deleteTermLocked(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	if i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+}
+
+// searchScratch holds per-query buffers indexed by slot, reused between
+// queries so large catalogs don't allocate on every search.
+type searchScratch struct {
+	total   []float64 // summed score over tokens
+	current []float64 // best score for the token being processed
+	matched []int32   // number of tokens matched so far
+	touched []int32
+	first   []int32 // slots matched by the first token
+}
+
+func (sc *searchScratch) grow(n int) {
+	if len(sc.total) < n {
+		sc.total = make([]float64, n)
+		sc.current = make([]float64, n)
+		sc.matched = make([]int32, n)
+	}
+}
+
+// search returns every product matching all query tokens and the filter.
+// A token matches a whole term or a term prefix. An empty query matches
+// every product with score 0. Hits are unordered.
+func (idx *searchIndex) search(query string, filter searchFilter) []searchHit {
+	tokens := tokenize(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(tokens) == 0 {
+		if strings.TrimSpace(query) != "" {
+			return nil // only punctuation, nothing can match
+		}
+		hits := make([]searchHit, 0, len(idx.slots))
+		for i := range idx.docs {
+			doc := &idx.docs[i]
+			if doc.productID != 0 && filter.matches(doc) {
+				hits = append(hits, doc.hit(0))
+			}
+		}
+		return hits
+	}
+
+	sc, _ := idx.scratch.Get().(*searchScratch)
+	if sc == nil {
+		sc = &searchScratch{}
+	}
+	defer idx.scratch.Put(sc)
+	sc.grow(len(idx.docs))
+
+	docCount := float64(len(idx.slots))
+	for i, token := range tokens {
+		sc.touched = sc.touched[:0]
+
+		// Best-scoring matching term per product for this token
+		for j := sort.SearchStrings(idx.terms, token); j < len(idx.terms) && strings.HasPrefix(idx.terms[j], token); j++ {
+			term := idx.terms[j]
+			postings := idx.postings[term]
+
+			idf := math.Log(1 + docCount/float64(len(postings)))
+			boost := 1.0
+			if term != token {
+				boost = prefixPenalty * float64(len(token)) / float64(len(term))
+			}
+
+			for _, p := range postings {
+				score := float64(p.weight) * idf * boost
+				if sc.current[p.slot] == 0 {
+					sc.touched = append(sc.touched, p.slot)
+				}
+				if score > sc.current[p.slot] {
+					sc.current[p.slot] = score
+				}
+			}
+		}
+
+		// Keep only products that matched every token so far
+		for _, slot := range sc.touched {
+			if sc.matched[slot] == int32(i) {
+				sc.total[slot] += sc.current[slot]
+				sc.matched[slot]++
+			}
+			sc.current[slot] = 0
+		}
+		if i == 0 {
+			sc.first = append(sc.first[:0], sc.touched...)
+		}
+	}
+
+	hits := make([]searchHit, 0, len(sc.first))
+	want := int32(len(tokens))
+	for _, slot := range sc.first {
+		if sc.matched[slot] == want && filter.matches(&idx.docs[slot]) {
+			hits = append(hits, idx.docs[slot].hit(sc.total[slot]))
+		}
+		sc.total[slot] = 0
+		sc.matched[slot] = 0
+	}
+	return hits
+}
+
+// tokenize splits text into lower-cased words of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
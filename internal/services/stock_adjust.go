@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"go-ecommerce/internal/models"
+)
+
+var (
+	ErrZeroDelta     = errors.New("delta must not be zero")
+	ErrStockMismatch = errors.New("stock does not match expected_stock")
+	ErrNegativeStock = errors.New("adjustment would make stock negative")
+)
+
+// MaxAdjustments caps the lines of one AdjustStock call.
+const MaxAdjustments = 500
+
+// AdjustStock adds signed deltas to the stock of one or more SKUs, all or
+// nothing: if any line fails (unknown SKU, expected_stock mismatch, stock
+// going below zero) no stock changes. A SKU may appear only once per
+// location per call, so moving stock between locations is one call.
+// Units held by carts are not protected; an adjustment can take stock below
+// what is held.
+func (s *ProductService) AdjustStock(adjustments []models.StockAdjustment, change models.StockChange) ([]models.StockAdjustmentResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Validate every line before touching stock
+	results := make([]models.StockAdjustmentResult, 0, len(adjustments))
+	type line struct {
+		item     models.SKURef
+		location string
+	}
+	seen := make(map[line]bool, len(adjustments))
+	for _, adjustment := range adjustments {
+		item := adjustment.Ref()
+		if adjustment.Delta == 0 {
+			return nil, fmt.Errorf("%w: product %d %s", ErrZeroDelta, item.ProductID, item.SKU)
+		}
+		if seen[line{item, adjustment.Location}] {
+			return nil, fmt.Errorf("%w: product %d %q appears twice", ErrDuplicateSKU, item.ProductID, item.SKU)
+		}
+		seen[line{item, adjustment.Location}] = true
+
+		product, name, stock, err := s.unitLocked(item)
+		if err != nil {
+			return nil, err
+		}
+		if adjustment.Location != "" {
+			if !s.warehouses.known(adjustment.Location) {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownLocation, adjustment.Location)
+			}
+			name += " at " + adjustment.Location
+			stock = product.UnitLocations(item.SKU)[adjustment.Location]
+		}
+		if adjustment.ExpectedStock != nil && *adjustment.ExpectedStock != stock {
+			return nil, fmt.Errorf("%w for product %s: expected %d, is %d", ErrStockMismatch, name, *adjustment.ExpectedStock, stock)
+		}
+		// Stock owed to backorders may already be negative; only removals
+		// are held to zero
+		if adjustment.Delta < 0 && stock+adjustment.Delta < 0 {
+			return nil, fmt.Errorf("%w for product %s: %d%+d", ErrNegativeStock, name, stock, adjustment.Delta)
+		}
+		results = append(results, models.StockAdjustmentResult{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Location:  adjustment.Location,
+			OldStock:  stock,
+			NewStock:  stock + adjustment.Delta,
+		})
+	}
+
+	// Apply, undoing this call's own changes if a write fails
+	for i, adjustment := range adjustments {
+		if err := s.adjustLocatedLocked(adjustment.Ref(), adjustment.Delta, adjustmentLocations(adjustment, 1), change); err != nil {
+			undo := change
+			undo.Type = models.MovementAdjustment
+			undo.Reason = "adjustment rolled back"
+			for _, done := range adjustments[:i] {
+				s.adjustLocatedLocked(done.Ref(), -done.Delta, adjustmentLocations(done, -1), undo)
+			}
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// adjustmentLocations is the location part of an adjustment, times sign.
+func adjustmentLocations(adjustment models.StockAdjustment, sign int) []models.LocationQuantity {
+	if adjustment.Location == "" {
+		return nil
+	}
+	return []models.LocationQuantity{{Location: adjustment.Location, Quantity: sign * adjustment.Delta}}
+}
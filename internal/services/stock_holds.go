@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+// DefaultHoldTTL is how long cart holds last without cart activity.
+const DefaultHoldTTL = 15 * time.Minute
+
+// cartHold is the stock set aside for one cart. Every change to the cart
+// pushes expiresAt back by the TTL.
+type cartHold struct {
+	items     map[models.SKURef]int
+	expiresAt time.Time
+}
+
+// stockHolds are soft reservations: units promised to carts but still in
+// Product.Stock until an order converts them. They live in memory only,
+// so a restart drops them. Guarded by ProductService.mu.
+type stockHolds struct {
+	ttl    time.Duration
+	carts  map[string]*cartHold
+	totals map[models.SKURef]int // held units per SKU across all carts
+}
+
+func newStockHolds() *stockHolds {
+	return &stockHolds{
+		ttl:    DefaultHoldTTL,
+		carts:  make(map[string]*cartHold),
+		totals: make(map[models.SKURef]int),
+	}
+}
+
+// set changes the units cartID holds of item; 0 drops the hold.
+func (h *stockHolds) set(cartID string, item models.SKURef, quantity int, now time.Time) {
+	hold, exists := h.carts[cartID]
+	if !exists {
+		if quantity <= 0 {
+			return
+		}
+		hold = &cartHold{items: make(map[models.SKURef]int)}
+		h.carts[cartID] = hold
+	}
+
+	h.addTotal(item, quantity-hold.items[item])
+	if quantity > 0 {
+		hold.items[item] = quantity
+	} else {
+		delete(hold.items, item)
+	}
+	hold.expiresAt = now.Add(h.ttl)
+
+	if len(hold.items) == 0 {
+		delete(h.carts, cartID)
+	}
+}
+
+// drop releases everything cartID holds.
+func (h *stockHolds) drop(cartID string) {
+	hold, exists := h.carts[cartID]
+	if !exists {
+		return
+	}
+	for item, quantity := range hold.items {
+		h.addTotal(item, -quantity)
+	}
+	delete(h.carts, cartID)
+}
+
+// others is how many units of item carts other than cartID hold.
+func (h *stockHolds) others(cartID string, item models.SKURef) int {
+	held := h.totals[item]
+	if hold, exists := h.carts[cartID]; exists {
+		held -= hold.items[item]
+	}
+	return held
+}
+
+func (h *stockHolds) addTotal(item models.SKURef, delta int) {
+	if total := h.totals[item] + delta; total > 0 {
+		h.totals[item] = total
+	} else {
+		delete(h.totals, item)
+	}
+}
+
+// SetHoldTTL changes how long cart holds last. Existing holds keep their
+// expiry until the cart changes again.
+func (s *ProductService) SetHoldTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.holds.ttl = ttl
+}
+
+// HoldStock sets how many units of item cartID holds, checking them
+// against stock not held by other carts. Lowering the quantity always
+// succeeds; 0 releases the hold. Products that take backorders hold only
+// what is free. It returns when the cart's holds expire.
+func (s *ProductService) HoldStock(cartID string, item models.SKURef, quantity int) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if quantity < 0 {
+		return time.Time{}, ErrInvalidQuantity
+	}
+	product, name, stock, err := s.unitLocked(item)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if available := s.availableLocked(cartID, item, stock); quantity > s.heldLocked(cartID, item) && available < quantity {
+		if !product.Backorderable(available, quantity) {
+			return time.Time{}, fmt.Errorf("%w for product %s", ErrInsufficientStock, name)
+		}
+		// Hold what is free; checkout backorders the rest
+		quantity = max(available, 0)
+	}
+
+	now := time.Now()
+	s.holds.set(cartID, item, quantity, now)
+	return now.Add(s.holds.ttl), nil
+}
+
+// ReleaseHold drops the hold cartID has on item, e.g. when the item leaves
+// the cart. Unlike HoldStock it works for products that are gone.
+func (s *ProductService) ReleaseHold(cartID string, item models.SKURef) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.holds.set(cartID, item, 0, time.Now())
+}
+
+// ReleaseHolds drops every hold of a cart, e.g. after checkout or when the
+// user starts a new cart.
+func (s *ProductService) ReleaseHolds(cartID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.holds.drop(cartID)
+}
+
+// ExpireHolds drops holds of carts idle past the TTL and returns how many
+// carts lost their holds.
+func (s *ProductService) ExpireHolds(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := 0
+	for cartID, hold := range s.holds.carts {
+		if now.After(hold.expiresAt) {
+			s.holds.drop(cartID)
+			expired++
+		}
+	}
+	return expired
+}
+
+// StartHoldSweeper expires idle cart holds every interval until ctx is
+// cancelled.
+func (s *ProductService) StartHoldSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.ExpireHolds(now)
+			}
+		}
+	}()
+}
+
+// heldLocked is how many units of item cartID holds. Caller holds s.mu.
+func (s *ProductService) heldLocked(cartID string, item models.SKURef) int {
+	if hold, exists := s.holds.carts[cartID]; exists {
+		return hold.items[item]
+	}
+	return 0
+}
+
+// availableLocked is a SKU's stock minus units held by carts other than
+// cartID ("" for none). Caller holds s.mu.
+func (s *ProductService) availableLocked(cartID string, item models.SKURef, stock int) int {
+	return stock - s.holds.others(cartID, item)
+}
+
+// totalAvailableLocked is the product's Available as withAvailability
+// fills it in. Caller holds s.mu.
+func (s *ProductService) totalAvailableLocked(product *models.Product) int {
+	if !product.HasVariants() {
+		return max(s.availableLocked("", models.SKURef{ProductID: product.ID}, product.Stock), 0)
+	}
+	total := 0
+	for _, variant := range product.Variants {
+		total += max(s.availableLocked("", models.SKURef{ProductID: product.ID, SKU: variant.SKU}, variant.Stock), 0)
+	}
+	return total
+}
+
+// withAvailability copies product and fills in Available on it and its
+// variants. Caller holds s.mu.
+func (s *ProductService) withAvailability(product *models.Product) models.Product {
+	view := *product
+	if !view.HasVariants() {
+		available := max(s.availableLocked("", models.SKURef{ProductID: view.ID}, view.Stock), 0)
+		view.Available = &available
+		return view
+	}
+
+	view.Variants = make([]models.Variant, len(product.Variants))
+	total := 0
+	for i, variant := range product.Variants {
+		available := max(s.availableLocked("", models.SKURef{ProductID: view.ID, SKU: variant.SKU}, variant.Stock), 0)
+		variant.Available = &available
+		view.Variants[i] = variant
+		total += available
+	}
+	view.Available = &total
+	return view
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+// DefaultReorderPoint is the reorder point of products that don't set one.
+const DefaultReorderPoint = 5
+
+// stockMonitor remembers the last seen level of every SKU so a drop is
+// reported once. Guarded by ProductService.mu, except listeners, which are
+// registered at startup.
+type stockMonitor struct {
+	reorderPoint int
+	levels       map[models.SKURef]models.StockLevel
+	listeners    []func(models.StockAlert)
+}
+
+func newStockMonitor() *stockMonitor {
+	return &stockMonitor{
+		reorderPoint: DefaultReorderPoint,
+		levels:       make(map[models.SKURef]models.StockLevel),
+	}
+}
+
+// SetReorderPoint changes the store-wide default reorder point.
+func (s *ProductService) SetReorderPoint(reorderPoint int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.monitor.reorderPoint = reorderPoint
+}
+
+// OnStockAlert registers fn to be called for every stock alert. Register
+// listeners before starting the monitor; fn runs on the monitor goroutine.
+func (s *ProductService) OnStockAlert(fn func(models.StockAlert)) {
+	s.monitor.listeners = append(s.monitor.listeners, fn)
+}
+
+// reorderPointLocked is the reorder point that applies to product. Caller
+// holds s.mu.
+func (s *ProductService) reorderPointLocked(product *models.Product) int {
+	if product.ReorderPoint > 0 {
+		return product.ReorderPoint
+	}
+	return s.monitor.reorderPoint
+}
+
+// CheckStockLevels compares every SKU's stock with its reorder point and
+// raises an alert for each SKU that became low or ran out since the last
+// check. The first check reports everything already low.
+func (s *ProductService) CheckStockLevels(now time.Time) []models.StockAlert {
+	s.mu.Lock()
+
+	var alerts []models.StockAlert
+	levels := make(map[models.SKURef]models.StockLevel, len(s.monitor.levels))
+	for _, product := range s.activeProducts() {
+		reorderPoint := s.reorderPointLocked(product)
+		for sku, stock := range stockLevels(product) {
+			item := models.SKURef{ProductID: product.ID, SKU: sku}
+			level := models.LevelOf(stock, reorderPoint)
+			levels[item] = level
+			if level == models.StockOK || level == s.monitor.levels[item] {
+				continue
+			}
+			name, _, _, _ := product.Unit(sku)
+			alerts = append(alerts, models.StockAlert{
+				ProductID:    item.ProductID,
+				SKU:          sku,
+				Name:         name,
+				Level:        level,
+				Stock:        stock,
+				ReorderPoint: reorderPoint,
+				At:           now,
+			})
+		}
+	}
+	s.monitor.levels = levels
+	s.mu.Unlock()
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].ProductID != alerts[j].ProductID {
+			return alerts[i].ProductID < alerts[j].ProductID
+		}
+		return alerts[i].SKU < alerts[j].SKU
+	})
+	// Outside the lock, so listeners may call back into the service
+	for _, alert := range alerts {
+		for _, listener := range s.monitor.listeners {
+			listener(alert)
+		}
+	}
+	return alerts
+}
+
+// StartStockMonitor checks stock levels every interval until ctx is
+// cancelled.
+func (s *ProductService) StartStockMonitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		s.CheckStockLevels(time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.CheckStockLevels(now)
+			}
+		}
+	}()
+}
+
+// LowStock lists every SKU at or below its reorder point, emptiest first.
+// Velocity and reorder suggestions are left for SuggestReorder.
+func (s *ProductService) LowStock() []models.LowStockItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]models.LowStockItem, 0)
+	for _, product := range s.activeProducts() {
+		reorderPoint := s.reorderPointLocked(product)
+		for sku, stock := range stockLevels(product) {
+			level := models.LevelOf(stock, reorderPoint)
+			if level == models.StockOK {
+				continue
+			}
+			item := models.SKURef{ProductID: product.ID, SKU: sku}
+			name, _, _, _ := product.Unit(sku)
+			items = append(items, models.LowStockItem{
+				ProductID:    item.ProductID,
+				SKU:          sku,
+				Name:         name,
+				Level:        level,
+				Stock:        stock,
+				Available:    max(s.availableLocked("", item, stock), 0),
+				ReorderPoint: reorderPoint,
+			})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Stock != items[j].Stock {
+			return items[i].Stock < items[j].Stock
+		}
+		if items[i].ProductID != items[j].ProductID {
+			return items[i].ProductID < items[j].ProductID
+		}
+		return items[i].SKU < items[j].SKU
+	})
+	return items
+}
+
+// SuggestReorder fills in the sales velocity and reorder suggestion of
+// each item from sold, the units ordered per SKU over the last windowDays:
+// enough to cover coverDays of sales at that rate, plus the reorder point
+// as safety stock, minus what is on hand.
+func SuggestReorder(items []models.LowStockItem, sold map[models.SKURef]int, windowDays, coverDays int) {
+	for i := range items {
+		item := &items[i]
+		item.Sold = sold[models.SKURef{ProductID: item.ProductID, SKU: item.SKU}]
+		item.DailyVelocity = float64(item.Sold) / float64(windowDays)
+		demand := int(math.Ceil(item.DailyVelocity * float64(coverDays)))
+		item.SuggestedReorder = max(demand+item.ReorderPoint-item.Stock, 0)
+	}
+}
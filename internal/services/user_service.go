@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"go-ecommerce/internal/auth"
+	"go-ecommerce/internal/models"
+	"go-ecommerce/internal/repository"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailTaken         = errors.New("email already registered")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// SampleUserPassword is the password of every user created by InitSampleUsers.
+const SampleUserPassword = "password123"
+
+// sampleEmail is the email of sample user i.
+func sampleEmail(i int) string {
+	return fmt.Sprintf("user%d@example.com", i)
+}
+
+type UserService struct {
+	mu     sync.RWMutex
+	repo   repository.UserRepository
+	tokens *auth.TokenManager
+	nextID int
+
+	// compared against when the email is unknown, so a failed login takes
+	// the same time whether or not the account exists
+	dummyHash []byte
+}
+
+func NewUserService(repo repository.UserRepository, tokens *auth.TokenManager) *UserService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return &UserService{
+		repo:      repo,
+		tokens:    tokens,
+		nextID:    repo.MaxID() + 1,
+		dummyHash: dummyHash,
+	}
+}
+
+// InitSampleUsers creates users 1..n (userN@example.com) so the load test
+// scripts have real accounts to act as. User 1 is an admin and user 2 is
+// staff. Does nothing if users already exist.
+func (s *UserService) InitSampleUsers(n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repo.Count() > 0 {
+		return nil
+	}
+
+	// Hashing once keeps startup fast; every sample user shares the password
+	hash, err := bcrypt.GenerateFromPassword([]byte(SampleUserPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := 1; i <= n; i++ {
+		role := models.RoleCustomer
+		switch i {
+		case 1:
+			role = models.RoleAdmin
+		case 2:
+			role = models.RoleStaff
+		}
+
+		user := &models.User{
+			ID:           i,
+			Email:        sampleEmail(i),
+			Name:         fmt.Sprintf("User %d", i),
+			Role:         role,
+			PasswordHash: string(hash),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if err := s.repo.Save(user); err != nil {
+			return err
+		}
+	}
+	s.nextID = n + 1
+	return nil
+}
+
+// SampleStaffActive reports whether the sample admin or staff account
+// (users 1 and 2 of InitSampleUsers) still has SampleUserPassword, which
+// anyone reading the code knows. Sample users are stored like any other,
+// so a persistent store keeps them after dev mode is turned off.
+func (s *UserService) SampleStaffActive() bool {
+	s.mu.RLock()
+	var hashes []string
+	for _, i := range []int{1, 2} {
+		if user, exists := s.repo.FindByEmail(sampleEmail(i)); exists {
+			hashes = append(hashes, user.PasswordHash)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(SampleUserPassword)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *UserService) Register(req models.RegisterRequest) (*models.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email := normalizeEmail(req.Email)
+	if _, exists := s.repo.FindByEmail(email); exists {
+		return nil, ErrEmailTaken
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:           s.nextID,
+		Email:        email,
+		Name:         strings.TrimSpace(req.Name),
+		Role:         models.RoleCustomer,
+		PasswordHash: string(hash),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.repo.Save(user); err != nil {
+		return nil, err
+	}
+	s.nextID++
+
+	return user, nil
+}
+
+// Login checks the credentials and issues a bearer token.
+func (s *UserService) Login(email, password string) (string, time.Time, *models.User, error) {
+	s.mu.RLock()
+	user, exists := s.repo.FindByEmail(normalizeEmail(email))
+	s.mu.RUnlock()
+
+	if !exists {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return "", time.Time{}, nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return "", time.Time{}, nil, ErrInvalidCredentials
+	}
+
+	token, expiresAt, err := s.tokens.Issue(auth.Identity{UserID: user.ID})
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	return token, expiresAt, user, nil
+}
+
+func (s *UserService) GetUser(id int) (*models.User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.repo.FindByID(id)
+}
+
+// RoleOf returns the user's current role. Accounts stored before roles
+// existed count as customers.
+func (s *UserService) RoleOf(id int) (models.Role, bool) {
+	user, exists := s.GetUser(id)
+	if !exists {
+		return "", false
+	}
+	if user.Role == "" {
+		return models.RoleCustomer, true
+	}
+	return user.Role, true
+}
+
+// EnsureAdmin creates an admin account for email, or promotes the existing
+// account, so a fresh deployment has someone who can reach /api/admin.
+func (s *UserService) EnsureAdmin(email, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email = normalizeEmail(email)
+	if user, exists := s.repo.FindByEmail(email); exists {
+		if user.Role == models.RoleAdmin {
+			return nil
+		}
+		user.Role = models.RoleAdmin
+		user.UpdatedAt = time.Now()
+		return s.repo.Save(user)
+	}
+
+	if len(password) < 8 {
+		return fmt.Errorf("admin password for %s must be at least 8 characters", email)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:           s.nextID,
+		Email:        email,
+		Name:         "Administrator",
+		Role:         models.RoleAdmin,
+		PasswordHash: string(hash),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.repo.Save(user); err != nil {
+		return err
+	}
+	s.nextID++
+	return nil
+}
+
+func (s *UserService) UpdateRole(id int, role models.Role) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.repo.FindByID(id)
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	if err := s.repo.Save(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Exists reports whether id belongs to a registered user.
+func (s *UserService) Exists(id int) bool {
+	_, exists := s.GetUser(id)
+	return exists
+}
+
+func (s *UserService) UpdateProfile(id int, req models.UpdateProfileRequest) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.repo.FindByID(id)
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	if req.Name != "" {
+		user.Name = strings.TrimSpace(req.Name)
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := s.repo.Save(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword swaps the password hash after checking the current one.
+// bcrypt runs outside the lock; if the hash changed meanwhile the current
+// password is no longer the one that was checked.
+func (s *UserService) ChangePassword(id int, currentPassword, newPassword string) error {
+	s.mu.RLock()
+	user, exists := s.repo.FindByID(id)
+	var oldHash string
+	if exists {
+		oldHash = user.PasswordHash
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		return ErrUserNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(oldHash), []byte(currentPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists = s.repo.FindByID(id)
+	if !exists {
+		return ErrUserNotFound
+	}
+	if user.PasswordHash != oldHash {
+		return ErrInvalidCredentials
+	}
+	user.PasswordHash = string(hash)
+	user.UpdatedAt = time.Now()
+
+	return s.repo.Save(user)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"go-ecommerce/internal/auth"
+	"go-ecommerce/internal/repository"
+)
+
+func TestSampleStaffActiveUntilPasswordsChange(t *testing.T) {
+	users := NewUserService(repository.NewMemoryUserRepository(), auth.NewTokenManager([]byte("test-secret"), 0))
+	if users.SampleStaffActive() {
+		t.Fatal("SampleStaffActive on an empty store")
+	}
+	if err := users.InitSampleUsers(3); err != nil {
+		t.Fatalf("InitSampleUsers: %v", err)
+	}
+	if !users.SampleStaffActive() {
+		t.Fatal("SampleStaffActive = false with the sample passwords in place")
+	}
+
+	for _, id := range []int{1, 2} {
+		if err := users.ChangePassword(id, SampleUserPassword, "a-private-password"); err != nil {
+			t.Fatalf("ChangePassword(%d): %v", id, err)
+		}
+	}
+	if users.SampleStaffActive() {
+		t.Error("SampleStaffActive = true after the staff passwords changed")
+	}
+}
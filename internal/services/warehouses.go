@@ -0,0 +1,306 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-ecommerce/internal/models"
+)
+
+// Multi-location stock: a SKU that tracks locations keeps its stock per
+// warehouse code in Locations, and its Stock stays the total. Stock not
+// placed in a location (total minus located) can still be sold; when
+// negative it is what backorders owe. Reservations take units from
+// locations in the order the allocation strategy ranks them, then from the
+// unplaced rest.
+
+var (
+	ErrUnknownLocation  = errors.New("unknown location")
+	ErrLocationRequired = errors.New("stock is tracked per location, a location is required")
+	ErrInvalidWarehouse = errors.New("invalid warehouse configuration")
+)
+
+const DefaultAllocationStrategy = models.AllocateSingleLocation
+
+// warehouseConfig is guarded by ProductService.mu.
+type warehouseConfig struct {
+	list     []models.Warehouse // by priority, then code
+	strategy models.AllocationStrategy
+}
+
+func newWarehouseConfig() *warehouseConfig {
+	return &warehouseConfig{strategy: DefaultAllocationStrategy}
+}
+
+func (c *warehouseConfig) known(code string) bool {
+	for _, warehouse := range c.list {
+		if warehouse.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// SetWarehouses replaces the warehouses stock can be kept in and the
+// strategy orders are allocated with. Stock at codes no longer listed is
+// kept and still sold, after the listed locations.
+func (s *ProductService) SetWarehouses(warehouses []models.Warehouse, strategy models.AllocationStrategy) error {
+	if !strategy.IsValid() {
+		return fmt.Errorf("%w: unknown strategy %q", ErrInvalidWarehouse, strategy)
+	}
+	seen := make(map[string]bool, len(warehouses))
+	for _, warehouse := range warehouses {
+		if warehouse.Code == "" || seen[warehouse.Code] {
+			return fmt.Errorf("%w: missing or duplicate code %q", ErrInvalidWarehouse, warehouse.Code)
+		}
+		seen[warehouse.Code] = true
+	}
+
+	list := append([]models.Warehouse(nil), warehouses...)
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority < list[j].Priority
+		}
+		return list[i].Code < list[j].Code
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.warehouses.list = list
+	s.warehouses.strategy = strategy
+	return nil
+}
+
+// Warehouses returns the configured warehouses and allocation strategy.
+func (s *ProductService) Warehouses() ([]models.Warehouse, models.AllocationStrategy) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.Warehouse(nil), s.warehouses.list...), s.warehouses.strategy
+}
+
+// rankLocationsLocked orders the warehouses to take quantities from, best
+// first, by the allocation strategy. shipTo may be nil (no address, e.g.
+// a direct purchase). Caller holds s.mu.
+func (s *ProductService) rankLocationsLocked(shipTo *models.ShippingAddress, quantities map[models.SKURef]int) []string {
+	ranked := append([]models.Warehouse(nil), s.warehouses.list...)
+
+	switch s.warehouses.strategy {
+	case models.AllocateNearest:
+		if shipTo != nil {
+			sort.SliceStable(ranked, func(i, j int) bool {
+				return proximity(ranked[i], shipTo) > proximity(ranked[j], shipTo)
+			})
+		}
+	case models.AllocateLowestCost:
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return ranked[i].CostPerUnit < ranked[j].CostPerUnit
+		})
+	case models.AllocateSingleLocation:
+		complete := make(map[string]bool, len(ranked))
+		for _, warehouse := range ranked {
+			complete[warehouse.Code] = s.hasAllLocked(warehouse.Code, quantities)
+		}
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return complete[ranked[i].Code] && !complete[ranked[j].Code]
+		})
+	}
+
+	codes := make([]string, len(ranked))
+	for i, warehouse := range ranked {
+		codes[i] = warehouse.Code
+	}
+	return codes
+}
+
+// hasAllLocked reports whether location can fill every quantity of SKUs
+// that track locations. Caller holds s.mu.
+func (s *ProductService) hasAllLocked(location string, quantities map[models.SKURef]int) bool {
+	for item, quantity := range quantities {
+		product, exists := s.repo.FindByID(item.ProductID)
+		if !exists {
+			continue
+		}
+		if locations := product.UnitLocations(item.SKU); locations != nil && locations[location] < quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// proximity is a rough closeness of a warehouse to an address, higher is
+// closer: same country first, then same city, then a longer shared postal
+// code prefix. There is no geocoding.
+func proximity(warehouse models.Warehouse, address *models.ShippingAddress) int {
+	if !strings.EqualFold(warehouse.Country, address.Country) {
+		return 0
+	}
+	score := 100
+	if strings.EqualFold(strings.TrimSpace(warehouse.City), strings.TrimSpace(address.City)) {
+		score += 10
+	}
+	for i := 0; i < len(warehouse.PostalCode) && i < len(address.PostalCode) && i < 9; i++ {
+		if warehouse.PostalCode[i] != address.PostalCode[i] {
+			break
+		}
+		score++
+	}
+	return score
+}
+
+// planLocations picks up to quantity units of sku from its locations,
+// visiting ranked codes first and any other stocked codes after them. It
+// changes nothing.
+func planLocations(product *models.Product, sku string, quantity int, ranked []string) []models.LocationQuantity {
+	locations := product.UnitLocations(sku)
+	if len(locations) == 0 {
+		return nil
+	}
+
+	order := append([]string(nil), ranked...)
+	var others []string
+	for code := range locations {
+		listed := false
+		for _, rankedCode := range ranked {
+			if rankedCode == code {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			others = append(others, code)
+		}
+	}
+	sort.Strings(others)
+	order = append(order, others...)
+
+	var plan []models.LocationQuantity
+	remaining := quantity
+	for _, code := range order {
+		if remaining == 0 {
+			break
+		}
+		take := min(remaining, locations[code])
+		if take <= 0 {
+			continue
+		}
+		plan = append(plan, models.LocationQuantity{Location: code, Quantity: take})
+		remaining -= take
+	}
+	return plan
+}
+
+// negated flips the sign of every quantity, turning units taken from
+// locations into units put back.
+func negated(parts []models.LocationQuantity) []models.LocationQuantity {
+	flipped := make([]models.LocationQuantity, len(parts))
+	for i, part := range parts {
+		flipped[i] = models.LocationQuantity{Location: part.Location, Quantity: -part.Quantity}
+	}
+	return flipped
+}
+
+// mergeLocations returns allocations plus parts, one entry per location.
+// allocations itself is not changed.
+func mergeLocations(allocations []models.LocationQuantity, parts ...models.LocationQuantity) []models.LocationQuantity {
+	allocations = append([]models.LocationQuantity(nil), allocations...)
+	for _, part := range parts {
+		merged := false
+		for i := range allocations {
+			if allocations[i].Location == part.Location {
+				allocations[i].Quantity += part.Quantity
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			allocations = append(allocations, part)
+		}
+	}
+	return allocations
+}
+
+// markAllocations records on each order line which locations its units
+// come from.
+func markAllocations(items []models.OrderItem, allocations map[models.SKURef][]models.LocationQuantity) {
+	for i := range items {
+		ref := items[i].Ref()
+		items[i].Allocations = allocations[ref]
+		delete(allocations, ref)
+	}
+}
+
+// SetLocationStock sets a SKU's stock at one warehouse, e.g. after a count,
+// and returns what it was. The SKU's total moves by the difference, except
+// that an increase first places stock that is not in any location yet.
+// With expected set it only writes if the location still has *expected.
+func (s *ProductService) SetLocationStock(item models.SKURef, location string, newStock int, expected *int, change models.StockChange) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.warehouses.known(location) {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownLocation, location)
+	}
+	product, _, stock, err := s.unitLocked(item)
+	if err != nil {
+		return 0, err
+	}
+	locations := product.UnitLocations(item.SKU)
+	old := locations[location]
+	if expected != nil && *expected != old {
+		return old, fmt.Errorf("%w: expected %d, is %d at %s", ErrStockMismatch, *expected, old, location)
+	}
+
+	delta := newStock - old
+	placed := 0
+	if delta > 0 {
+		placed = min(delta, max(stock-models.LocatedStock(locations), 0))
+	}
+	product.AddLocationStock(item.SKU, location, delta)
+	product.AddStock(item.SKU, delta-placed)
+	product.UpdatedAt = time.Now()
+	if err := s.repo.Save(product); err != nil {
+		return old, err
+	}
+	if delta == placed {
+		return old, nil
+	}
+	return old, s.appendMovementLocked(item, delta-placed, stock+delta-placed, change)
+}
+
+// PickLocations assigns up to quantity units of item that already left
+// its total (backorders) to the locations where restocks have put them,
+// ranked for shipTo. It returns what it assigned.
+func (s *ProductService) PickLocations(item models.SKURef, quantity int, shipTo *models.ShippingAddress) ([]models.LocationQuantity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.repo.FindByID(item.ProductID)
+	if !exists {
+		return nil, nil
+	}
+	_, _, stock, ok := product.Unit(item.SKU)
+	if !ok {
+		return nil, nil
+	}
+	// Located units the total no longer counts are owed to backorders
+	owed := models.LocatedStock(product.UnitLocations(item.SKU)) - stock
+	if owed <= 0 {
+		return nil, nil
+	}
+
+	ranked := s.rankLocationsLocked(shipTo, map[models.SKURef]int{item: quantity})
+	plan := planLocations(product, item.SKU, min(quantity, owed), ranked)
+	if len(plan) == 0 {
+		return nil, nil
+	}
+	for _, part := range plan {
+		product.AddLocationStock(item.SKU, part.Location, -part.Quantity)
+	}
+	product.UpdatedAt = time.Now()
+	return plan, s.repo.Save(product)
+}
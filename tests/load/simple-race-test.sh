@@ -27,7 +27,7 @@ echo ""
 # TEST 1: Purchase endpoint race
 echo "1. Testing purchase endpoint race condition..."
 echo "Resetting product 1 stock to 50..."
-curl -X PUT "http://localhost:8080/api/products/1/stock" \
+curl -X PUT "http://localhost:8080/api/admin/products/1/stock?user_id=1" \
   -H "Content-Type: application/json" \
   -d '{"stock": 50}' -s > /dev/null
 
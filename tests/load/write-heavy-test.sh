@@ -44,7 +44,7 @@ echo ""
 
 # Reset product stock to 100 for testing
 echo -e "${YELLOW}Resetting product stock...${NC}"
-curl -X PUT "http://localhost:8080/api/products/1/stock" \
+curl -X PUT "http://localhost:8080/api/admin/products/1/stock?user_id=1" \
   -H "Content-Type: application/json" \
   -d '{"stock": 100}' -s > /dev/null
 echo "Product 1 stock reset to 100"
@@ -121,7 +121,7 @@ echo -e "${RED}⚠️  WARNING: This will test inventory oversell!${NC}"
 echo -e "${YELLOW}1000 users trying to buy 100 available products${NC}"
 
 # Reset stock to 100
-curl -X PUT "http://localhost:8080/api/products/2/stock" \
+curl -X PUT "http://localhost:8080/api/admin/products/2/stock?user_id=1" \
   -H "Content-Type: application/json" \
   -d '{"stock": 100}' -s > /dev/null
 
@@ -147,7 +147,7 @@ echo -e "${YELLOW}Checking product 2 stock (should be 0, but might be negative!)
 curl -s "http://localhost:8080/api/products/2" | jq '.data.stock'
 
 echo -e "${YELLOW}Order stats:${NC}"
-curl -s "http://localhost:8080/api/orders/stats" | jq
+curl -s "http://localhost:8080/api/admin/orders/stats?user_id=1" | jq
 
 echo ""
 # ==========================================
@@ -181,7 +181,7 @@ cat > order_targets.txt << EOF
 POST http://localhost:8080/api/orders?user_id=2001&mode=unsafe
 Content-Type: application/json
 
-{"cart_id": "${cart_ids[0]}", "address": "Test Address", "payment_method": "cash"}
+{"cart_id": "${cart_ids[0]}", "address": {"name": "Load Test", "line1": "Test Address", "city": "Jakarta", "postal_code": "10110", "country": "ID"}, "payment": {"method": "cash_on_delivery"}}
 EOF
 
 echo -e "${YELLOW}Testing order creation race condition...${NC}"
@@ -198,7 +198,7 @@ echo "Product 3 stock: $INITIAL_STOCK"
 
 # Count successful orders
 echo -e "${YELLOW}Counting successful orders...${NC}"
-STATS=$(curl -s "http://localhost:8080/api/orders/stats")
+STATS=$(curl -s "http://localhost:8080/api/admin/orders/stats?user_id=1")
 TOTAL_ORDERS=$(echo $STATS | jq '.stats.total_orders')
 FAILED_ORDERS=$(echo $STATS | jq '.stats.failed_orders')
 RACE_CONDITIONS=$(echo $STATS | jq '.stats.race_conditions')
@@ -238,7 +238,7 @@ echo "6. PURCHASE ENDPOINT RACE CONDITION"
 echo "========================================="
 
 # Reset product 5 stock
-curl -X PUT "http://localhost:8080/api/products/5/stock" \
+curl -X PUT "http://localhost:8080/api/admin/products/5/stock?user_id=1" \
   -H "Content-Type: application/json" \
   -d '{"stock": 50}' -s > /dev/null
 
@@ -287,7 +287,7 @@ done
 
 echo ""
 echo -e "${YELLOW}Order Statistics:${NC}"
-curl -s "http://localhost:8080/api/orders/stats" | jq '.stats'
+curl -s "http://localhost:8080/api/admin/orders/stats?user_id=1" | jq '.stats'
 
 echo ""
 echo -e "${GREEN}✅ Load test completed!${NC}"